@@ -17,8 +17,16 @@ package azureeventhubreceiver // import "github.com/open-telemetry/opentelemetry
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/Azure/azure-amqp-common-go/v4/conn"
 	eventhub "github.com/Azure/azure-event-hubs-go/v3"
+	"github.com/Azure/azure-event-hubs-go/v3/persist"
+	"github.com/cenkalti/backoff/v4"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/obsreport"
@@ -29,25 +37,47 @@ import (
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/stanza/adapter"
 )
 
+// partitionKeyAttribute is the log attribute holding the partition key of the event.
+const partitionKeyAttribute = "azure.eventhub.partition_key"
+
+// Resource attributes identifying the Event Hub the logs were received from.
+const hubNameAttribute = "azure.eventhub.name"
+const hubNamespaceAttribute = "azure.eventhub.namespace"
+
+// parseErrorAttribute is the log attribute holding the error of an event that could not be
+// parsed, kept in the raw format when Config.OnParseError is "raw".
+const parseErrorAttribute = "azure.eventhub.parse_error"
+
 type client struct {
 	settings receiver.CreateSettings
 	consumer consumer.Logs
 	config   *Config
 	obsrecv  *obsreport.Receiver
 	hub      hubWrapper
-	convert  eventConverter
+	// unmarshaler translates events to logs according to Config.Format.
+	unmarshaler eventUnmarshaler
+	// namespace and hubName identify the Event Hub of the connection, and its checkpoints, set at Start.
+	namespace string
+	hubName   string
+	// checkpoints holds the checkpoints of the partitions, which take precedence over Config.StartFrom.
+	checkpoints *storageCheckpointPersister
+	// newBackOff creates the backoff used to resubscribe to partitions.
+	newBackOff func() backoff.BackOff
+	ctx        context.Context
+	cancel     context.CancelFunc
+	// lastSequence and lastOffset hold the sequence number and the offset of the last event received from each partition.
+	lastSequence   map[string]int64
+	lastOffset     map[string]string
+	lastSequenceMu sync.Mutex
 }
 
 type hubWrapper interface {
 	GetRuntimeInformation(ctx context.Context) (*eventhub.HubRuntimeInformation, error)
+	GetPartitionInformation(ctx context.Context, partitionID string) (*eventhub.HubPartitionRuntimeInformation, error)
 	Receive(ctx context.Context, partitionID string, handler eventhub.Handler, opts ...eventhub.ReceiveOption) (listerHandleWrapper, error)
 	Close(ctx context.Context) error
 }
 
-type eventConverter interface {
-	ToLogs(event *eventhub.Event) (plog.Logs, error)
-}
-
 type listerHandleWrapper interface {
 	Done() <-chan struct{}
 	Err() error
@@ -61,6 +91,10 @@ func (h *hubWrapperImpl) GetRuntimeInformation(ctx context.Context) (*eventhub.H
 	return h.hub.GetRuntimeInformation(ctx)
 }
 
+func (h *hubWrapperImpl) GetPartitionInformation(ctx context.Context, partitionID string) (*eventhub.HubPartitionRuntimeInformation, error) {
+	return h.hub.GetPartitionInformation(ctx, partitionID)
+}
+
 func (h *hubWrapperImpl) Receive(ctx context.Context, partitionID string, handler eventhub.Handler, opts ...eventhub.ReceiveOption) (listerHandleWrapper, error) {
 	l, err := h.hub.Receive(ctx, partitionID, handler, opts...)
 	return l, err
@@ -71,12 +105,20 @@ func (h *hubWrapperImpl) Close(ctx context.Context) error {
 }
 
 func (c *client) Start(ctx context.Context, host component.Host) error {
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	if c.newBackOff == nil { // set manually for testing.
+		c.newBackOff = newResubscribeBackOff
+	}
 	storageClient, err := adapter.GetStorageClient(ctx, host, c.config.StorageID, c.settings.ID)
 	if err != nil {
 		return err
 	}
+	c.checkpoints = &storageCheckpointPersister{storageClient: storageClient}
+	if c.namespace, c.hubName, err = hubIdentity(c.config.Connection); err != nil {
+		return err
+	}
 	if c.hub == nil { // set manually for testing.
-		hub, newHubErr := eventhub.NewHubFromConnectionString(c.config.Connection, eventhub.HubWithOffsetPersistence(&storageCheckpointPersister{storageClient: storageClient}))
+		hub, newHubErr := eventhub.NewHubFromConnectionString(c.config.Connection, eventhub.HubWithOffsetPersistence(c.checkpoints))
 		if newHubErr != nil {
 			return newHubErr
 		}
@@ -105,16 +147,59 @@ func (c *client) Start(ctx context.Context, host component.Host) error {
 			return err
 		}
 	}
+	if c.config.LagPollInterval > 0 {
+		go c.pollPartitionLag(c.config.LagPollInterval)
+	}
 	return nil
 }
 
+// setUpOnePartition starts receiving events from a partition at the configured offset when
+// applyOffset is set, from its checkpoint when it has one, and from the configured start
+// position otherwise.
 func (c *client) setUpOnePartition(ctx context.Context, partitionID string, applyOffset bool) error {
-	offsetOption := eventhub.ReceiveWithLatestOffset()
 	if applyOffset && c.config.Offset != "" {
-		offsetOption = eventhub.ReceiveWithStartingOffset(c.config.Offset)
+		return c.receivePartition(ctx, partitionID, eventhub.ReceiveWithStartingOffset(c.config.Offset))
+	}
+	checkpointed, err := c.checkpoints.hasCheckpoint(c.namespace, c.hubName, eventhub.DefaultConsumerGroup, partitionID)
+	if err != nil {
+		return err
+	}
+	if checkpointed {
+		return c.receivePartition(ctx, partitionID)
+	}
+	offsetOption, err := startOption(c.config.StartFrom)
+	if err != nil {
+		return err
+	}
+	return c.receivePartition(ctx, partitionID, offsetOption)
+}
+
+// startOption returns the receive option starting a partition at the start position:
+// the latest offset, the earliest offset, or an enqueued time.
+func startOption(startFrom string) (eventhub.ReceiveOption, error) {
+	switch startFrom {
+	case "", latestStartPosition:
+		return eventhub.ReceiveWithLatestOffset(), nil
+	case earliestStartPosition:
+		return eventhub.ReceiveWithStartingOffset(persist.StartOfStream), nil
+	}
+	enqueuedTime, err := time.Parse(time.RFC3339, startFrom)
+	if err != nil {
+		return nil, err
 	}
+	return eventhub.ReceiveFromTimestamp(enqueuedTime), nil
+}
 
-	handle, err := c.hub.Receive(ctx, partitionID, c.handle, offsetOption)
+// receivePartition starts receiving events from a partition, as the exclusive
+// owner of the partition when an epoch is configured.
+func (c *client) receivePartition(ctx context.Context, partitionID string, opts ...eventhub.ReceiveOption) error {
+	if c.config.Epoch > 0 {
+		opts = append(opts, eventhub.ReceiveWithEpoch(c.config.Epoch))
+	}
+	handle, err := c.hub.Receive(ctx, partitionID, func(ctx context.Context, event *eventhub.Event) error {
+		c.recordSequence(partitionID, event)
+		return c.handle(ctx, event)
+	}, opts...)
 	if err != nil {
 		return err
 	}
@@ -123,24 +208,229 @@ func (c *client) setUpOnePartition(ctx context.Context, partitionID string, appl
 		err := handle.Err()
 		if err != nil {
 			c.settings.Logger.Error("Error reported by event hub", zap.Error(err))
+			c.resubscribePartition(partitionID)
 		}
 	}()
 
 	return nil
 }
 
+// resubscribePartition receives again from a partition whose receiver was closed
+// with an error, for example after a transient network error, or because another
+// receiver with a higher epoch took ownership of the partition. It retries with
+// jittered exponential backoff until it succeeds or the client shuts down, and
+// resumes after the last event received from the partition, or from where the
+// partition was started when no event was received.
+func (c *client) resubscribePartition(partitionID string) {
+	if c.ctx.Err() != nil {
+		return
+	}
+	c.settings.Logger.Warn("Event hub partition receiver closed, resubscribing", zap.String("partition", partitionID))
+	err := backoff.RetryNotify(func() error {
+		return c.resumePartition(c.ctx, partitionID)
+	}, backoff.WithContext(c.newBackOff(), c.ctx), func(err error, wait time.Duration) {
+		c.settings.Logger.Warn("Failed to resubscribe to event hub partition",
+			zap.String("partition", partitionID), zap.Duration("retry_in", wait), zap.Error(err))
+	})
+	if err != nil {
+		c.settings.Logger.Error("Stopped resubscribing to event hub partition", zap.String("partition", partitionID), zap.Error(err))
+		return
+	}
+	c.settings.Logger.Info("Resubscribed to event hub partition", zap.String("partition", partitionID))
+}
+
+// resumePartition receives from a partition after the last event received from it. Checkpoints
+// are not relied upon, as they are not kept without a storage extension, in which case the
+// whole partition would be received again.
+func (c *client) resumePartition(ctx context.Context, partitionID string) error {
+	c.lastSequenceMu.Lock()
+	offset, ok := c.lastOffset[partitionID]
+	c.lastSequenceMu.Unlock()
+	if ok {
+		return c.receivePartition(ctx, partitionID, eventhub.ReceiveWithStartingOffset(offset))
+	}
+	return c.setUpOnePartition(ctx, partitionID, c.config.Partition != "")
+}
+
+// newResubscribeBackOff returns an exponential backoff, randomized so that the
+// partitions of a receiver and of its replicas do not retry in lockstep.
+func newResubscribeBackOff() backoff.BackOff {
+	b := backoff.NewExponentialBackOff()
+	// Keep retrying until the receiver shuts down.
+	b.MaxElapsedTime = 0
+	return b
+}
+
+// recordSequence records the sequence number and the offset of the last event received from a partition.
+func (c *client) recordSequence(partitionID string, event *eventhub.Event) {
+	if event.SystemProperties == nil {
+		return
+	}
+	c.lastSequenceMu.Lock()
+	defer c.lastSequenceMu.Unlock()
+	if event.SystemProperties.SequenceNumber != nil {
+		if c.lastSequence == nil {
+			c.lastSequence = map[string]int64{}
+		}
+		c.lastSequence[partitionID] = *event.SystemProperties.SequenceNumber
+	}
+	if event.SystemProperties.Offset != nil {
+		if c.lastOffset == nil {
+			c.lastOffset = map[string]string{}
+		}
+		c.lastOffset[partitionID] = strconv.FormatInt(*event.SystemProperties.Offset, 10)
+	}
+}
+
+// pollPartitionLag reports the lag of the partitions every interval until the client shuts down.
+func (c *client) pollPartitionLag(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			c.recordPartitionLag(c.ctx)
+		}
+	}
+}
+
+// recordPartitionLag reports, for each partition an event was received from, the
+// number of events enqueued in the partition after the last received event.
+func (c *client) recordPartitionLag(ctx context.Context) {
+	c.lastSequenceMu.Lock()
+	lastSequence := make(map[string]int64, len(c.lastSequence))
+	for partitionID, sequence := range c.lastSequence {
+		lastSequence[partitionID] = sequence
+	}
+	c.lastSequenceMu.Unlock()
+
+	for partitionID, sequence := range lastSequence {
+		info, err := c.hub.GetPartitionInformation(ctx, partitionID)
+		if err != nil {
+			c.settings.Logger.Warn("Failed to get event hub partition information", zap.String("partition", partitionID), zap.Error(err))
+			continue
+		}
+		lag := info.LastSequenceNumber - sequence
+		if lag < 0 {
+			lag = 0
+		}
+		_ = stats.RecordWithTags(ctx, []tag.Mutator{
+			tag.Upsert(tagInstanceName, c.settings.ID.String()),
+			tag.Upsert(tagPartition, partitionID),
+		}, statPartitionLag.M(lag))
+	}
+}
+
 func (c *client) handle(ctx context.Context, event *eventhub.Event) error {
-	logs, err := c.convert.ToLogs(event)
+	ctx = c.obsrecv.StartLogsOp(ctx)
+	logs, err := c.toLogs(event)
 	if err != nil {
-		return fmt.Errorf("failed to convert logs: %w", err)
+		switch parseErrorMode(c.config.OnParseError) {
+		case dropOnParseError:
+			c.recordParseFailure(ctx, err)
+			c.settings.Logger.Debug("Dropped an event that could not be parsed", zap.Error(err))
+			return nil
+		case rawOnParseError:
+			c.countParseFailure(ctx)
+			if logs, err = c.toParseErrorLogs(event, err); err != nil {
+				c.obsrecv.EndLogsOp(ctx, "azureeventhub", 1, err)
+				return err
+			}
+		default:
+			c.recordParseFailure(ctx, err)
+			return err
+		}
+	}
+	setHubAttributes(logs, c.namespace, c.hubName)
+	if event.PartitionKey != nil {
+		setPartitionKey(logs, *event.PartitionKey)
 	}
-	c.obsrecv.StartLogsOp(ctx)
 	consumerErr := c.consumer.ConsumeLogs(ctx, logs)
 	c.obsrecv.EndLogsOp(ctx, "azureeventhub", logs.LogRecordCount(), consumerErr)
 	return consumerErr
 }
 
+// toLogs decompresses the data of an event and translates it to logs.
+func (c *client) toLogs(event *eventhub.Event) (plog.Logs, error) {
+	data, err := decompress(decompression(c.config.Decompression), event.Data, maxDecompressedSize)
+	if err != nil {
+		return plog.Logs{}, fmt.Errorf("failed to decompress event: %w", err)
+	}
+	event.Data = data
+	logs, err := c.unmarshaler.ToLogs(event)
+	if err != nil {
+		return plog.Logs{}, fmt.Errorf("failed to convert logs: %w", err)
+	}
+	return logs, nil
+}
+
+// toParseErrorLogs translates an event that could not be parsed as in the raw format,
+// with the parse error as an attribute of its log record.
+func (c *client) toParseErrorLogs(event *eventhub.Event, parseErr error) (plog.Logs, error) {
+	logs, err := newRawConverter(c.settings, c.config).ToLogs(event)
+	if err != nil {
+		return logs, fmt.Errorf("failed to convert logs: %w", err)
+	}
+	logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes().PutStr(parseErrorAttribute, parseErr.Error())
+	return logs, nil
+}
+
+// hubIdentity returns the namespace and the name of the Event Hub of a connection string,
+// read from its Endpoint and EntityPath keys.
+func hubIdentity(connection string) (namespace string, hubName string, err error) {
+	parsed, err := conn.ParsedConnectionFromStr(connection)
+	if err != nil {
+		return "", "", err
+	}
+	return parsed.Namespace, parsed.HubName, nil
+}
+
+// setHubAttributes sets the namespace and name of the Event Hub, when not empty, as
+// resource attributes of all the resources translated from an event.
+func setHubAttributes(logs plog.Logs, namespace string, hubName string) {
+	for i := 0; i < logs.ResourceLogs().Len(); i++ {
+		attrs := logs.ResourceLogs().At(i).Resource().Attributes()
+		if namespace != "" {
+			attrs.PutStr(hubNamespaceAttribute, namespace)
+		}
+		if hubName != "" {
+			attrs.PutStr(hubNameAttribute, hubName)
+		}
+	}
+}
+
+// setPartitionKey sets the partition key attribute on all the log records
+// translated from an event.
+func setPartitionKey(logs plog.Logs, partitionKey string) {
+	for i := 0; i < logs.ResourceLogs().Len(); i++ {
+		scopeLogs := logs.ResourceLogs().At(i).ScopeLogs()
+		for j := 0; j < scopeLogs.Len(); j++ {
+			logRecords := scopeLogs.At(j).LogRecords()
+			for k := 0; k < logRecords.Len(); k++ {
+				logRecords.At(k).Attributes().PutStr(partitionKeyAttribute, partitionKey)
+			}
+		}
+	}
+}
+
+// recordParseFailure reports an event that could not be translated as a
+// refused log record, and counts it separately from consumer failures.
+func (c *client) recordParseFailure(ctx context.Context, err error) {
+	c.countParseFailure(ctx)
+	c.obsrecv.EndLogsOp(ctx, "azureeventhub", 1, err)
+}
+
+// countParseFailure counts an event that could not be translated.
+func (c *client) countParseFailure(ctx context.Context) {
+	_ = stats.RecordWithTags(ctx, []tag.Mutator{tag.Upsert(tagInstanceName, c.settings.ID.String())}, statParseFailures.M(1))
+}
+
 func (c *client) Shutdown(ctx context.Context) error {
+	if c.cancel != nil {
+		c.cancel()
+	}
 	if c.hub == nil {
 		return nil
 	}
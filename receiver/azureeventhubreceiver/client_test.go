@@ -15,13 +15,23 @@
 package azureeventhubreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/azureeventhubreceiver"
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	eventhub "github.com/Azure/azure-event-hubs-go/v3"
+	"github.com/Azure/azure-event-hubs-go/v3/persist"
+	"github.com/cenkalti/backoff/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/component/componenttest"
 	"go.opentelemetry.io/collector/consumer/consumertest"
@@ -41,6 +51,13 @@ func (m mockHubWrapper) GetRuntimeInformation(ctx context.Context) (*eventhub.Hu
 	}, nil
 }
 
+func (m mockHubWrapper) GetPartitionInformation(_ context.Context, partitionID string) (*eventhub.HubPartitionRuntimeInformation, error) {
+	return &eventhub.HubPartitionRuntimeInformation{
+		PartitionID:        partitionID,
+		LastSequenceNumber: 42,
+	}, nil
+}
+
 func (m mockHubWrapper) Receive(ctx context.Context, partitionID string, handler eventhub.Handler, opts ...eventhub.ReceiveOption) (listerHandleWrapper, error) {
 	return &mockListenerHandleWrapper{
 		ctx: context.Background(),
@@ -63,15 +80,86 @@ func (m mockListenerHandleWrapper) Err() error {
 	return nil
 }
 
+// epochMockHubWrapper fails the next failures calls to Receive, and records the
+// options of every call. Each listener it returns can be closed with an error, as
+// when another receiver takes ownership of the partition.
+type epochMockHubWrapper struct {
+	mockHubWrapper
+	mu        sync.Mutex
+	failures  int
+	opts      [][]eventhub.ReceiveOption
+	handler   eventhub.Handler
+	listeners []chan struct{}
+}
+
+func (m *epochMockHubWrapper) Receive(_ context.Context, _ string, handler eventhub.Handler, opts ...eventhub.ReceiveOption) (listerHandleWrapper, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.opts = append(m.opts, opts)
+	m.handler = handler
+	if m.failures > 0 {
+		m.failures--
+		return nil, errors.New("receiver with higher epoch exists")
+	}
+	done := make(chan struct{})
+	m.listeners = append(m.listeners, done)
+	return &closableListenerHandleWrapper{done: done}, nil
+}
+
+// receiveOpts returns the number of options of every call to Receive.
+func (m *epochMockHubWrapper) receiveOpts() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := make([]int, len(m.opts))
+	for i, opts := range m.opts {
+		counts[i] = len(opts)
+	}
+	return counts
+}
+
+// lastReceiveOpts returns the options of the last call to Receive.
+func (m *epochMockHubWrapper) lastReceiveOpts() []eventhub.ReceiveOption {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.opts[len(m.opts)-1]
+}
+
+// receive passes an event to the handler of the last call to Receive.
+func (m *epochMockHubWrapper) receive(ctx context.Context, event *eventhub.Event) error {
+	m.mu.Lock()
+	handler := m.handler
+	m.mu.Unlock()
+	return handler(ctx, event)
+}
+
+func (m *epochMockHubWrapper) loseOwnership(failures int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures = failures
+	close(m.listeners[len(m.listeners)-1])
+}
+
+type closableListenerHandleWrapper struct {
+	done chan struct{}
+}
+
+func (m *closableListenerHandleWrapper) Done() <-chan struct{} {
+	return m.done
+}
+
+func (m *closableListenerHandleWrapper) Err() error {
+	return errors.New("ownership lost")
+}
+
 func TestClient_Start(t *testing.T) {
 	config := createDefaultConfig()
 	config.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
 
 	c := &client{
-		settings: receivertest.NewNopCreateSettings(),
-		consumer: consumertest.NewNop(),
-		config:   config.(*Config),
-		convert:  &rawConverter{},
+		settings:    receivertest.NewNopCreateSettings(),
+		consumer:    consumertest.NewNop(),
+		config:      config.(*Config),
+		unmarshaler: &rawConverter{},
 	}
 	c.hub = &mockHubWrapper{}
 	err := c.Start(context.Background(), componenttest.NewNopHost())
@@ -93,11 +181,11 @@ func TestClient_handle(t *testing.T) {
 	})
 	require.NoError(t, err)
 	c := &client{
-		settings: receivertest.NewNopCreateSettings(),
-		consumer: sink,
-		config:   config.(*Config),
-		obsrecv:  obsrecv,
-		convert:  &rawConverter{},
+		settings:    receivertest.NewNopCreateSettings(),
+		consumer:    sink,
+		config:      config.(*Config),
+		obsrecv:     obsrecv,
+		unmarshaler: &rawConverter{},
 	}
 	c.hub = &mockHubWrapper{}
 	err = c.Start(context.Background(), componenttest.NewNopHost())
@@ -125,3 +213,583 @@ func TestClient_handle(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, "bar", read.AsString())
 }
+
+func TestClient_handleCompressed(t *testing.T) {
+	config := createDefaultConfig()
+	config.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+	config.(*Config).Decompression = string(autoDecompression)
+
+	sink := new(consumertest.LogsSink)
+	obsrecv, err := obsreport.NewReceiver(obsreport.ReceiverSettings{
+		ReceiverID:             component.NewID(typeStr),
+		ReceiverCreateSettings: receivertest.NewNopCreateSettings(),
+	})
+	require.NoError(t, err)
+	c := &client{
+		settings:    receivertest.NewNopCreateSettings(),
+		consumer:    sink,
+		config:      config.(*Config),
+		obsrecv:     obsrecv,
+		unmarshaler: &rawConverter{},
+	}
+	err = c.handle(context.Background(), &eventhub.Event{
+		Data:             gzipBytes(t, []byte("hello")),
+		SystemProperties: &eventhub.SystemProperties{},
+	})
+	assert.NoError(t, err)
+	require.Len(t, sink.AllLogs(), 1)
+	assert.Equal(t, []byte("hello"), sink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Bytes().AsRaw())
+}
+
+func TestClient_handleCompressedTruncated(t *testing.T) {
+	config := createDefaultConfig()
+	config.(*Config).Decompression = string(gzipDecompression)
+	config.(*Config).MaxEventSize = 1024
+
+	sink := new(consumertest.LogsSink)
+	obsrecv, err := obsreport.NewReceiver(obsreport.ReceiverSettings{
+		ReceiverID:             component.NewID(typeStr),
+		ReceiverCreateSettings: receivertest.NewNopCreateSettings(),
+	})
+	require.NoError(t, err)
+	c := &client{
+		settings:    receivertest.NewNopCreateSettings(),
+		consumer:    sink,
+		config:      config.(*Config),
+		obsrecv:     obsrecv,
+		unmarshaler: newRawConverter(receivertest.NewNopCreateSettings(), config.(*Config)),
+	}
+	// The data inflates above max_event_size, but below the decompression limit.
+	data := bytes.Repeat([]byte("a"), 4096)
+	require.NoError(t, c.handle(context.Background(), &eventhub.Event{
+		Data:             gzipBytes(t, data),
+		SystemProperties: &eventhub.SystemProperties{},
+	}))
+
+	require.Len(t, sink.AllLogs(), 1)
+	lr := sink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, data[:1024], lr.Body().Bytes().AsRaw())
+	truncated, ok := lr.Attributes().Get(truncatedAttribute)
+	require.True(t, ok)
+	assert.True(t, truncated.Bool())
+	originalSize, ok := lr.Attributes().Get(originalSizeAttribute)
+	require.True(t, ok)
+	assert.Equal(t, int64(len(data)), originalSize.Int())
+	_, ok = lr.Attributes().Get(parseErrorAttribute)
+	assert.False(t, ok)
+}
+
+func TestClient_handleAzureEnvelope(t *testing.T) {
+	config := createDefaultConfig()
+	config.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+
+	sink := new(consumertest.LogsSink)
+	obsrecv, err := obsreport.NewReceiver(obsreport.ReceiverSettings{
+		ReceiverID:             component.NewID(typeStr),
+		ReceiverCreateSettings: receivertest.NewNopCreateSettings(),
+	})
+	require.NoError(t, err)
+	c := &client{
+		settings:    receivertest.NewNopCreateSettings(),
+		consumer:    sink,
+		config:      config.(*Config),
+		obsrecv:     obsrecv,
+		unmarshaler: newAzureLogFormatConverter(receivertest.NewNopCreateSettings()),
+	}
+	data, err := os.ReadFile(filepath.Join("testdata", "log-minimum-3.json"))
+	require.NoError(t, err)
+	err = c.handle(context.Background(), &eventhub.Event{
+		Data:             data,
+		SystemProperties: &eventhub.SystemProperties{},
+	})
+	require.NoError(t, err)
+
+	// All the records of the envelope share a single resource.
+	require.Len(t, sink.AllLogs(), 1)
+	logs := sink.AllLogs()[0]
+	assert.Equal(t, 3, logs.LogRecordCount())
+	require.Equal(t, 1, logs.ResourceLogs().Len())
+	require.Equal(t, 1, logs.ResourceLogs().At(0).ScopeLogs().Len())
+	resourceID, ok := logs.ResourceLogs().At(0).Resource().Attributes().Get(azureResourceID)
+	require.True(t, ok)
+	assert.Equal(t, "/RESOURCE_ID", resourceID.Str())
+}
+
+func TestClient_handlePartitionKey(t *testing.T) {
+	config := createDefaultConfig()
+	config.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+
+	sink := new(consumertest.LogsSink)
+	obsrecv, err := obsreport.NewReceiver(obsreport.ReceiverSettings{
+		ReceiverID:             component.NewID(typeStr),
+		ReceiverCreateSettings: receivertest.NewNopCreateSettings(),
+	})
+	require.NoError(t, err)
+	c := &client{
+		settings:    receivertest.NewNopCreateSettings(),
+		consumer:    sink,
+		config:      config.(*Config),
+		obsrecv:     obsrecv,
+		unmarshaler: newAzureLogFormatConverter(receivertest.NewNopCreateSettings()),
+	}
+	data, err := os.ReadFile(filepath.Join("testdata", "log-minimum-3.json"))
+	require.NoError(t, err)
+	partitionKey := "customer-42"
+	err = c.handle(context.Background(), &eventhub.Event{
+		Data:             data,
+		PartitionKey:     &partitionKey,
+		SystemProperties: &eventhub.SystemProperties{},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, sink.AllLogs(), 1)
+	logRecords := sink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	require.Equal(t, 3, logRecords.Len())
+	for i := 0; i < logRecords.Len(); i++ {
+		read, ok := logRecords.At(i).Attributes().Get(partitionKeyAttribute)
+		require.True(t, ok)
+		assert.Equal(t, partitionKey, read.Str())
+	}
+
+	// Events without a partition key do not get the attribute.
+	sink.Reset()
+	err = c.handle(context.Background(), &eventhub.Event{
+		Data:             data,
+		SystemProperties: &eventhub.SystemProperties{},
+	})
+	require.NoError(t, err)
+	require.Len(t, sink.AllLogs(), 1)
+	_, ok := sink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes().Get(partitionKeyAttribute)
+	assert.False(t, ok)
+}
+
+func TestHubIdentity(t *testing.T) {
+	tests := []struct {
+		name          string
+		connection    string
+		wantNamespace string
+		wantHubName   string
+		wantErr       bool
+	}{
+		{
+			name:          "sample",
+			connection:    "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName",
+			wantNamespace: "namespace",
+			wantHubName:   "hubName",
+		},
+		{
+			name:          "sovereign_cloud",
+			connection:    "Endpoint=sb://contoso-logs.servicebus.chinacloudapi.cn/;SharedAccessKeyName=listen;SharedAccessKey=secret;EntityPath=insights-logs",
+			wantNamespace: "contoso-logs",
+			wantHubName:   "insights-logs",
+		},
+		{
+			name:          "namespace_connection",
+			connection:    "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=",
+			wantNamespace: "namespace",
+		},
+		{
+			name:       "missing_endpoint",
+			connection: "SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName",
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace, hubName, err := hubIdentity(tt.connection)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNamespace, namespace)
+			assert.Equal(t, tt.wantHubName, hubName)
+		})
+	}
+}
+
+func TestClient_handleHubAttributes(t *testing.T) {
+	config := createDefaultConfig()
+	config.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+
+	sink := new(consumertest.LogsSink)
+	obsrecv, err := obsreport.NewReceiver(obsreport.ReceiverSettings{
+		ReceiverID:             component.NewID(typeStr),
+		ReceiverCreateSettings: receivertest.NewNopCreateSettings(),
+	})
+	require.NoError(t, err)
+	c := &client{
+		settings:    receivertest.NewNopCreateSettings(),
+		consumer:    sink,
+		config:      config.(*Config),
+		obsrecv:     obsrecv,
+		unmarshaler: newCommonSchemaConverter(receivertest.NewNopCreateSettings(), config.(*Config)),
+	}
+	c.hub = &mockHubWrapper{}
+	require.NoError(t, c.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		assert.NoError(t, c.Shutdown(context.Background()))
+	}()
+
+	// The items of the event are translated to a resource per cloud role.
+	data, err := os.ReadFile(filepath.Join("testdata", "common-schema-traces.json"))
+	require.NoError(t, err)
+	require.NoError(t, c.handle(context.Background(), &eventhub.Event{Data: data, SystemProperties: &eventhub.SystemProperties{}}))
+
+	require.Len(t, sink.AllLogs(), 1)
+	resourceLogs := sink.AllLogs()[0].ResourceLogs()
+	require.Equal(t, 2, resourceLogs.Len())
+	for i := 0; i < resourceLogs.Len(); i++ {
+		attrs := resourceLogs.At(i).Resource().Attributes()
+		name, ok := attrs.Get(hubNameAttribute)
+		require.True(t, ok)
+		assert.Equal(t, "hubName", name.Str())
+		namespace, ok := attrs.Get(hubNamespaceAttribute)
+		require.True(t, ok)
+		assert.Equal(t, "namespace", namespace.Str())
+	}
+}
+
+func TestClient_handleParseFailure(t *testing.T) {
+	tests := []struct {
+		name         string
+		onParseError string
+		wantErr      bool
+		wantLogs     bool
+	}{
+		{
+			name:    "default",
+			wantErr: true,
+		},
+		{
+			name:         "fail",
+			onParseError: string(failOnParseError),
+			wantErr:      true,
+		},
+		{
+			name:         "raw",
+			onParseError: string(rawOnParseError),
+			wantLogs:     true,
+		},
+		{
+			name:         "drop",
+			onParseError: string(dropOnParseError),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view.Unregister(MetricViews()...)
+			views := MetricViews()
+			require.NoError(t, view.Register(views...))
+			defer view.Unregister(views...)
+
+			config := createDefaultConfig()
+			config.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+			config.(*Config).BodyEncoding = string(stringBodyEncoding)
+			config.(*Config).OnParseError = tt.onParseError
+
+			sink := new(consumertest.LogsSink)
+			obsrecv, err := obsreport.NewReceiver(obsreport.ReceiverSettings{
+				ReceiverID:             component.NewID(typeStr),
+				ReceiverCreateSettings: receivertest.NewNopCreateSettings(),
+			})
+			require.NoError(t, err)
+			c := &client{
+				settings:    receivertest.NewNopCreateSettings(),
+				consumer:    sink,
+				config:      config.(*Config),
+				obsrecv:     obsrecv,
+				unmarshaler: newAzureLogFormatConverter(receivertest.NewNopCreateSettings()),
+				hubName:     "hubName",
+			}
+			partitionKey := "tenant-1"
+			err = c.handle(context.Background(), &eventhub.Event{
+				Data:             []byte("not json"),
+				PartitionKey:     &partitionKey,
+				SystemProperties: &eventhub.SystemProperties{},
+			})
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "failed to convert logs")
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if tt.wantLogs {
+				require.Len(t, sink.AllLogs(), 1)
+				resourceLogs := sink.AllLogs()[0].ResourceLogs().At(0)
+				hubName, ok := resourceLogs.Resource().Attributes().Get(hubNameAttribute)
+				require.True(t, ok)
+				assert.Equal(t, "hubName", hubName.Str())
+				lr := resourceLogs.ScopeLogs().At(0).LogRecords().At(0)
+				assert.Equal(t, "not json", lr.Body().Str())
+				parseErr, ok := lr.Attributes().Get(parseErrorAttribute)
+				require.True(t, ok)
+				assert.Contains(t, parseErr.Str(), "failed to convert logs")
+				key, ok := lr.Attributes().Get(partitionKeyAttribute)
+				require.True(t, ok)
+				assert.Equal(t, partitionKey, key.Str())
+			} else {
+				assert.Len(t, sink.AllLogs(), 0)
+			}
+
+			// Events that cannot be parsed are counted whatever the mode.
+			viewData, err := view.RetrieveData(statParseFailures.Name())
+			require.NoError(t, err)
+			require.Len(t, viewData, 1)
+			assert.Equal(t, float64(1), viewData[0].Data.(*view.SumData).Value)
+		})
+	}
+}
+
+func TestClient_handleDecompressionFailure(t *testing.T) {
+	config := createDefaultConfig()
+	config.(*Config).Decompression = string(gzipDecompression)
+	config.(*Config).OnParseError = string(rawOnParseError)
+
+	sink := new(consumertest.LogsSink)
+	obsrecv, err := obsreport.NewReceiver(obsreport.ReceiverSettings{
+		ReceiverID:             component.NewID(typeStr),
+		ReceiverCreateSettings: receivertest.NewNopCreateSettings(),
+	})
+	require.NoError(t, err)
+	c := &client{
+		settings:    receivertest.NewNopCreateSettings(),
+		consumer:    sink,
+		config:      config.(*Config),
+		obsrecv:     obsrecv,
+		unmarshaler: &rawConverter{},
+	}
+	require.NoError(t, c.handle(context.Background(), &eventhub.Event{
+		Data:             []byte("not gzip"),
+		SystemProperties: &eventhub.SystemProperties{},
+	}))
+
+	// The data is kept as received.
+	require.Len(t, sink.AllLogs(), 1)
+	lr := sink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, []byte("not gzip"), lr.Body().Bytes().AsRaw())
+	parseErr, ok := lr.Attributes().Get(parseErrorAttribute)
+	require.True(t, ok)
+	assert.Contains(t, parseErr.Str(), "failed to decompress event")
+}
+
+func TestClient_epochReacquiresPartition(t *testing.T) {
+	config := createDefaultConfig()
+	config.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+	config.(*Config).Partition = "foo"
+	config.(*Config).Epoch = 5
+
+	hub := &epochMockHubWrapper{}
+	c := &client{
+		settings:    receivertest.NewNopCreateSettings(),
+		consumer:    consumertest.NewNop(),
+		config:      config.(*Config),
+		unmarshaler: &rawConverter{},
+		hub:         hub,
+		newBackOff:  func() backoff.BackOff { return &backoff.ZeroBackOff{} },
+	}
+	require.NoError(t, c.Start(context.Background(), componenttest.NewNopHost()))
+	// The offset option and the epoch option.
+	assert.Equal(t, []int{2}, hub.receiveOpts())
+
+	hub.loseOwnership(2)
+	// Two failed attempts, then a successful one, all starting from the configured offset
+	// as no event was received.
+	assert.Eventually(t, func() bool {
+		return len(hub.receiveOpts()) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []int{2, 2, 2, 2}, hub.receiveOpts())
+
+	require.NoError(t, c.Shutdown(context.Background()))
+	hub.loseOwnership(0)
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, hub.receiveOpts(), 4, "no reacquisition after shutdown")
+}
+
+func TestClient_resubscribesOnReceiveError(t *testing.T) {
+	config := createDefaultConfig()
+	config.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+	config.(*Config).Partition = "foo"
+
+	hub := &epochMockHubWrapper{}
+	c := &client{
+		settings:    receivertest.NewNopCreateSettings(),
+		consumer:    consumertest.NewNop(),
+		config:      config.(*Config),
+		unmarshaler: &rawConverter{},
+		hub:         hub,
+		newBackOff:  func() backoff.BackOff { return &backoff.ZeroBackOff{} },
+	}
+	require.NoError(t, c.Start(context.Background(), componenttest.NewNopHost()))
+	assert.Equal(t, []int{1}, hub.receiveOpts())
+
+	// The link fails, and the first attempt to receive again fails as well.
+	hub.loseOwnership(1)
+	assert.Eventually(t, func() bool {
+		return len(hub.receiveOpts()) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []int{1, 1, 1}, hub.receiveOpts())
+	require.NoError(t, c.Shutdown(context.Background()))
+}
+
+func TestClient_resubscribeResumesAfterLastEvent(t *testing.T) {
+	config := createDefaultConfig()
+	config.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+	config.(*Config).Partition = "foo"
+	config.(*Config).StartFrom = earliestStartPosition
+
+	hub := &epochMockHubWrapper{}
+	obsrecv, err := obsreport.NewReceiver(obsreport.ReceiverSettings{
+		ReceiverID:             component.NewID(typeStr),
+		ReceiverCreateSettings: receivertest.NewNopCreateSettings(),
+	})
+	require.NoError(t, err)
+	c := &client{
+		settings:    receivertest.NewNopCreateSettings(),
+		consumer:    consumertest.NewNop(),
+		config:      config.(*Config),
+		obsrecv:     obsrecv,
+		unmarshaler: &rawConverter{},
+		hub:         hub,
+		newBackOff:  func() backoff.BackOff { return &backoff.ZeroBackOff{} },
+	}
+	// Without storage extension, no checkpoint is kept.
+	require.NoError(t, c.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		assert.NoError(t, c.Shutdown(context.Background()))
+	}()
+
+	// Before any event is received, the partition is received again from the start position.
+	hub.loseOwnership(0)
+	assert.Eventually(t, func() bool {
+		return len(hub.receiveOpts()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Len(t, hub.lastReceiveOpts(), 1)
+	assert.True(t, sameReceiveOption(eventhub.ReceiveWithStartingOffset(persist.StartOfStream), hub.lastReceiveOpts()[0]))
+
+	// Then, after the last event received.
+	offset := int64(4096)
+	require.NoError(t, hub.receive(context.Background(), &eventhub.Event{
+		Data:             []byte("hello"),
+		SystemProperties: &eventhub.SystemProperties{Offset: &offset},
+	}))
+	hub.loseOwnership(0)
+	assert.Eventually(t, func() bool {
+		return len(hub.receiveOpts()) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Len(t, hub.lastReceiveOpts(), 1)
+	assert.True(t, sameReceiveOption(eventhub.ReceiveWithStartingOffset("4096"), hub.lastReceiveOpts()[0]))
+	assert.Equal(t, map[string]string{"foo": "4096"}, c.lastOffset)
+}
+
+// startMockHubWrapper records the options of the calls to Receive.
+type startMockHubWrapper struct {
+	mockHubWrapper
+	opts [][]eventhub.ReceiveOption
+}
+
+func (m *startMockHubWrapper) Receive(ctx context.Context, partitionID string, handler eventhub.Handler, opts ...eventhub.ReceiveOption) (listerHandleWrapper, error) {
+	m.opts = append(m.opts, opts)
+	return m.mockHubWrapper.Receive(ctx, partitionID, handler, opts...)
+}
+
+// sameReceiveOption reports whether two receive options were built by the same constructor.
+func sameReceiveOption(a, b eventhub.ReceiveOption) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+func TestClient_startPosition(t *testing.T) {
+	tests := []struct {
+		name       string
+		startFrom  string
+		offset     string
+		checkpoint bool
+		want       eventhub.ReceiveOption
+	}{
+		{
+			name: "default",
+			want: eventhub.ReceiveWithLatestOffset(),
+		},
+		{
+			name:      "latest",
+			startFrom: "latest",
+			want:      eventhub.ReceiveWithLatestOffset(),
+		},
+		{
+			name:      "earliest",
+			startFrom: "earliest",
+			want:      eventhub.ReceiveWithStartingOffset(persist.StartOfStream),
+		},
+		{
+			name:      "enqueued_time",
+			startFrom: "2023-02-01T10:00:00Z",
+			want:      eventhub.ReceiveFromTimestamp(time.Now()),
+		},
+		{
+			name:       "checkpoint_overrides_start_from",
+			startFrom:  "earliest",
+			checkpoint: true,
+		},
+		{
+			name:       "offset_overrides_checkpoint",
+			offset:     "1234-5566",
+			checkpoint: true,
+			want:       eventhub.ReceiveWithStartingOffset("1234-5566"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createDefaultConfig()
+			config.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+			config.(*Config).StartFrom = tt.startFrom
+			config.(*Config).Offset = tt.offset
+
+			storageClient := newMockClient()
+			checkpoints := &storageCheckpointPersister{storageClient: storageClient}
+			if tt.checkpoint {
+				require.NoError(t, checkpoints.Write("namespace", "hubName", eventhub.DefaultConsumerGroup, "foo", persist.NewCheckpoint("42", 7, time.Now())))
+			}
+			hub := &startMockHubWrapper{}
+			c := &client{
+				settings:    receivertest.NewNopCreateSettings(),
+				consumer:    consumertest.NewNop(),
+				config:      config.(*Config),
+				unmarshaler: &rawConverter{},
+				hub:         hub,
+				checkpoints: checkpoints,
+				namespace:   "namespace",
+				hubName:     "hubName",
+			}
+			require.NoError(t, c.setUpOnePartition(context.Background(), "foo", true))
+			require.Len(t, hub.opts, 1)
+			if tt.want == nil {
+				assert.Empty(t, hub.opts[0], "the stored checkpoint is used")
+				return
+			}
+			require.Len(t, hub.opts[0], 1)
+			assert.True(t, sameReceiveOption(tt.want, hub.opts[0][0]))
+		})
+	}
+}
+
+func TestClient_recordPartitionLag(t *testing.T) {
+	view.Unregister(MetricViews()...)
+	views := MetricViews()
+	require.NoError(t, view.Register(views...))
+	defer view.Unregister(views...)
+
+	c := &client{
+		settings: receivertest.NewNopCreateSettings(),
+		hub:      &mockHubWrapper{},
+	}
+	sequence := int64(40)
+	c.recordSequence("foo", &eventhub.Event{SystemProperties: &eventhub.SystemProperties{SequenceNumber: &sequence}})
+	c.recordSequence("bar", &eventhub.Event{SystemProperties: &eventhub.SystemProperties{}})
+	c.recordPartitionLag(context.Background())
+
+	viewData, err := view.RetrieveData(statPartitionLag.Name())
+	require.NoError(t, err)
+	require.Len(t, viewData, 1, "partitions without received events are not reported")
+	assert.Contains(t, viewData[0].Tags, tag.Tag{Key: tagPartition, Value: "foo"})
+	assert.Equal(t, float64(2), viewData[0].Data.(*view.LastValueData).Value)
+}
@@ -0,0 +1,246 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azureeventhubreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/azureeventhubreceiver"
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	eventhub "github.com/Azure/azure-event-hubs-go/v3"
+	jsoniter "github.com/json-iterator/go"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/receiver"
+	conventions "go.opentelemetry.io/collector/semconv/v1.13.0"
+	"go.uber.org/zap"
+)
+
+const aiInstrumentationKey = "azure.ai.instrumentation_key"
+const aiName = "azure.ai.name"
+const aiBaseType = "azure.ai.base_type"
+const aiTags = "azure.ai.tags"
+const aiData = "azure.ai.data"
+
+// Application Insights tags mapped to resource attributes and trace context.
+const aiCloudRoleTag = "ai.cloud.role"
+const aiCloudRoleInstanceTag = "ai.cloud.roleInstance"
+const aiOperationIDTag = "ai.operation.id"
+
+var errNotCommonSchema = errors.New("event does not follow the Application Insights common schema")
+
+// aiEnvelope represents a single Application Insights telemetry item
+// following the common schema, as exported via an Azure Event Hub:
+// https://learn.microsoft.com/en-us/azure/azure-monitor/app/data-model-complete
+type aiEnvelope struct {
+	Name string            `json:"name"`
+	Time string            `json:"time"`
+	IKey string            `json:"iKey"`
+	Tags map[string]string `json:"tags"`
+	Data *aiTelemetryData  `json:"data"`
+}
+
+type aiTelemetryData struct {
+	BaseType string                 `json:"baseType"`
+	BaseData map[string]interface{} `json:"baseData"`
+}
+
+// commonSchemaConverter maps the Application Insights telemetry items of events to log
+// records. Events that do not follow the common schema are kept as raw log records.
+type commonSchemaConverter struct {
+	buildInfo component.BuildInfo
+	logger    *zap.Logger
+	raw       *rawConverter
+}
+
+func newCommonSchemaConverter(settings receiver.CreateSettings, config *Config) *commonSchemaConverter {
+	return &commonSchemaConverter{
+		buildInfo: settings.BuildInfo,
+		logger:    settings.Logger,
+		raw:       newRawConverter(settings, config),
+	}
+}
+
+func (c *commonSchemaConverter) ToLogs(event *eventhub.Event) (plog.Logs, error) {
+	envelopes, err := decodeAIEnvelopes(event.Data)
+	if err != nil {
+		c.logger.Warn("Keeping the raw body of an event", zap.Error(err))
+		return c.raw.ToLogs(event)
+	}
+	return transformAIEnvelopes(c.buildInfo, envelopes)
+}
+
+// decodeAIEnvelopes decodes the telemetry items of the data, which holds items
+// or arrays of items, and fails if any of them does not follow the common schema.
+func decodeAIEnvelopes(data []byte) ([]aiEnvelope, error) {
+	var envelopes []aiEnvelope
+	decoder := jsoniter.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var value jsoniter.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		if trimmed := bytes.TrimSpace(value); len(trimmed) > 0 && trimmed[0] == '[' {
+			var items []aiEnvelope
+			if err := jsoniter.Unmarshal(value, &items); err != nil {
+				return nil, err
+			}
+			envelopes = append(envelopes, items...)
+			continue
+		}
+		var item aiEnvelope
+		if err := jsoniter.Unmarshal(value, &item); err != nil {
+			return nil, err
+		}
+		envelopes = append(envelopes, item)
+	}
+	if len(envelopes) == 0 {
+		return nil, errNotCommonSchema
+	}
+	for _, envelope := range envelopes {
+		if envelope.Time == "" || envelope.IKey == "" || envelope.Data == nil || envelope.Data.BaseType == "" {
+			return nil, errNotCommonSchema
+		}
+		if _, err := asTimestamp(envelope.Time); err != nil {
+			return nil, err
+		}
+	}
+	return envelopes, nil
+}
+
+// transformAIEnvelopes maps telemetry items to log records, grouped in resources
+// by the cloud role and role instance of the items.
+func transformAIEnvelopes(buildInfo component.BuildInfo, envelopes []aiEnvelope) (plog.Logs, error) {
+	l := plog.NewLogs()
+	logRecordsByRole := map[[2]string]plog.LogRecordSlice{}
+	for _, envelope := range envelopes {
+		role := [2]string{envelope.Tags[aiCloudRoleTag], envelope.Tags[aiCloudRoleInstanceTag]}
+		logRecords, ok := logRecordsByRole[role]
+		if !ok {
+			resourceLogs := l.ResourceLogs().AppendEmpty()
+			attrs := resourceLogs.Resource().Attributes()
+			attrs.PutStr(conventions.AttributeCloudProvider, conventions.AttributeCloudProviderAzure)
+			if role[0] != "" {
+				attrs.PutStr(conventions.AttributeServiceName, role[0])
+			}
+			if role[1] != "" {
+				attrs.PutStr(conventions.AttributeServiceInstanceID, role[1])
+			}
+			scopeLogs := resourceLogs.ScopeLogs().AppendEmpty()
+			scopeLogs.Scope().SetName(receiverScopeName)
+			scopeLogs.Scope().SetVersion(buildInfo.Version)
+			logRecords = scopeLogs.LogRecords()
+			logRecordsByRole[role] = logRecords
+		}
+		if err := aiEnvelopeToLogRecord(envelope, logRecords.AppendEmpty()); err != nil {
+			return l, err
+		}
+	}
+	return l, nil
+}
+
+// aiEnvelopeToLogRecord sets the fields and attributes of a log record from a telemetry
+// item. The body is the message of traces and exceptions, and the name of other items.
+func aiEnvelopeToLogRecord(envelope aiEnvelope, lr plog.LogRecord) error {
+	nanos, err := asTimestamp(envelope.Time)
+	if err != nil {
+		return err
+	}
+	lr.SetTimestamp(nanos)
+	if traceID, ok := asTraceID(envelope.Tags[aiOperationIDTag]); ok {
+		lr.SetTraceID(traceID)
+	}
+
+	baseData := envelope.Data.BaseData
+	if message, ok := baseData["message"].(string); ok {
+		lr.Body().SetStr(message)
+	} else if name, ok := baseData["name"].(string); ok {
+		lr.Body().SetStr(name)
+	}
+	if severity, severityText, ok := asAISeverity(baseData["severityLevel"]); ok {
+		lr.SetSeverityNumber(severity)
+		lr.SetSeverityText(severityText)
+	}
+
+	attrs := map[string]interface{}{
+		aiInstrumentationKey: envelope.IKey,
+		aiBaseType:           envelope.Data.BaseType,
+	}
+	if envelope.Name != "" {
+		attrs[aiName] = envelope.Name
+	}
+	if len(envelope.Tags) > 0 {
+		tags := make(map[string]interface{}, len(envelope.Tags))
+		for key, value := range envelope.Tags {
+			tags[key] = value
+		}
+		attrs[aiTags] = tags
+	}
+	if len(baseData) > 0 {
+		attrs[aiData] = baseData
+	}
+	if exceptions, ok := baseData["exceptions"].([]interface{}); ok && len(exceptions) > 0 {
+		if exception, ok := exceptions[0].(map[string]interface{}); ok {
+			if typeName, ok := exception["typeName"].(string); ok {
+				attrs[conventions.AttributeExceptionType] = typeName
+			}
+			if message, ok := exception["message"].(string); ok {
+				attrs[conventions.AttributeExceptionMessage] = message
+				if lr.Body().Type() == pcommon.ValueTypeEmpty {
+					lr.Body().SetStr(message)
+				}
+			}
+		}
+	}
+	return lr.Attributes().FromRaw(attrs)
+}
+
+// asTraceID parses the operation ID of a telemetry item as a trace ID, which it
+// is for items sent by W3C trace context enabled SDKs.
+func asTraceID(operationID string) (pcommon.TraceID, bool) {
+	var traceID pcommon.TraceID
+	if len(operationID) != hex.EncodedLen(len(traceID)) {
+		return traceID, false
+	}
+	if _, err := hex.Decode(traceID[:], []byte(operationID)); err != nil {
+		return pcommon.NewTraceIDEmpty(), false
+	}
+	return traceID, !traceID.IsEmpty()
+}
+
+// aiSeverityLevels are the severity levels of traces and exceptions, indexed by their number.
+var aiSeverityLevels = []struct {
+	name   string
+	number plog.SeverityNumber
+}{
+	{name: "Verbose", number: plog.SeverityNumberDebug},
+	{name: "Information", number: plog.SeverityNumberInfo},
+	{name: "Warning", number: plog.SeverityNumberWarn},
+	{name: "Error", number: plog.SeverityNumberError},
+	{name: "Critical", number: plog.SeverityNumberFatal},
+}
+
+// asAISeverity converts the severity level of traces and exceptions, given as a
+// number or a name, to the equivalent OpenTelemetry severity number, and returns
+// the name of the level.
+func asAISeverity(level interface{}) (plog.SeverityNumber, string, bool) {
+	for i, severityLevel := range aiSeverityLevels {
+		if level == float64(i) || level == severityLevel.name {
+			return severityLevel.number, severityLevel.name, true
+		}
+	}
+	return plog.SeverityNumberUnspecified, "", false
+}
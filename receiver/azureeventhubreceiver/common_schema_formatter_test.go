@@ -0,0 +1,155 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azureeventhubreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/azureeventhubreceiver"
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	eventhub "github.com/Azure/azure-event-hubs-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestCommonSchemaConverter_request(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "common-schema-request.json"))
+	require.NoError(t, err)
+	c := newCommonSchemaConverter(receivertest.NewNopCreateSettings(), createDefaultConfig().(*Config))
+	logs, err := c.ToLogs(&eventhub.Event{Data: data})
+	require.NoError(t, err)
+
+	require.Equal(t, 1, logs.ResourceLogs().Len())
+	resourceLogs := logs.ResourceLogs().At(0)
+	assert.Equal(t, map[string]interface{}{
+		"cloud.provider":      "azure",
+		"service.name":        "checkout",
+		"service.instance.id": "checkout-0",
+	}, resourceLogs.Resource().Attributes().AsRaw())
+	assert.Equal(t, receiverScopeName, resourceLogs.ScopeLogs().At(0).Scope().Name())
+
+	require.Equal(t, 1, logs.LogRecordCount())
+	lr := resourceLogs.ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, "GET /cart", lr.Body().Str())
+	assert.Equal(t, time.Date(2023, 2, 1, 10, 0, 0, 123456700, time.UTC), lr.Timestamp().AsTime())
+	assert.Equal(t, pcommon.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}, lr.TraceID())
+	assert.Equal(t, plog.SeverityNumberUnspecified, lr.SeverityNumber())
+	assert.Equal(t, map[string]interface{}{
+		aiInstrumentationKey: "IKEY",
+		aiName:               "Microsoft.ApplicationInsights.Request",
+		aiBaseType:           "RequestData",
+		aiTags: map[string]interface{}{
+			"ai.cloud.role":         "checkout",
+			"ai.cloud.roleInstance": "checkout-0",
+			"ai.operation.id":       "4bf92f3577b34da6a3ce929d0e0e4736",
+			"ai.operation.name":     "GET /cart",
+		},
+		aiData: map[string]interface{}{
+			"ver":          float64(2),
+			"id":           "00f067aa0ba902b7",
+			"name":         "GET /cart",
+			"duration":     "00:00:00.0230000",
+			"responseCode": "200",
+			"success":      true,
+			"url":          "https://shop.example.com/cart",
+		},
+	}, lr.Attributes().AsRaw())
+}
+
+func TestCommonSchemaConverter_traces(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "common-schema-traces.json"))
+	require.NoError(t, err)
+	c := newCommonSchemaConverter(receivertest.NewNopCreateSettings(), createDefaultConfig().(*Config))
+	logs, err := c.ToLogs(&eventhub.Event{Data: data})
+	require.NoError(t, err)
+
+	// The items of each cloud role get their own resource.
+	require.Equal(t, 2, logs.ResourceLogs().Len())
+
+	message := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, "Cart loaded", message.Body().Str())
+	assert.Equal(t, plog.SeverityNumberInfo, message.SeverityNumber())
+	assert.Equal(t, "Information", message.SeverityText())
+	assert.True(t, message.TraceID().IsEmpty())
+
+	serviceName, ok := logs.ResourceLogs().At(1).Resource().Attributes().Get("service.name")
+	require.True(t, ok)
+	assert.Equal(t, "payment", serviceName.Str())
+	exception := logs.ResourceLogs().At(1).ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, "Payment gateway timed out", exception.Body().Str())
+	assert.Equal(t, plog.SeverityNumberError, exception.SeverityNumber())
+	assert.Equal(t, "Error", exception.SeverityText())
+	exceptionType, ok := exception.Attributes().Get("exception.type")
+	require.True(t, ok)
+	assert.Equal(t, "System.TimeoutException", exceptionType.Str())
+}
+
+func TestCommonSchemaConverter_fallsBackToRaw(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "not_json",
+			data: "plain text",
+		},
+		{
+			name: "diagnostic_logs",
+			data: `{"records":[{"time":"2022-11-11T04:48:27.6767145Z","resourceId":"/RESOURCE_ID","operationName":"SecretGet","category":"AuditEvent"}]}`,
+		},
+		{
+			name: "one_unrecognized_item",
+			data: `[{"time":"2023-02-01T10:00:01Z","iKey":"IKEY","data":{"baseType":"MessageData","baseData":{"message":"hello"}}},{"time":"2023-02-01T10:00:01Z"}]`,
+		},
+		{
+			name: "invalid_time",
+			data: `{"time":"yesterday","iKey":"IKEY","data":{"baseType":"MessageData","baseData":{"message":"hello"}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, observed := observer.New(zapcore.WarnLevel)
+			settings := receivertest.NewNopCreateSettings()
+			settings.Logger = zap.New(core)
+			config := createDefaultConfig().(*Config)
+			config.BodyEncoding = string(stringBodyEncoding)
+			c := newCommonSchemaConverter(settings, config)
+
+			logs, err := c.ToLogs(&eventhub.Event{Data: []byte(tt.data), SystemProperties: &eventhub.SystemProperties{}})
+			require.NoError(t, err)
+			require.Equal(t, 1, logs.LogRecordCount())
+			assert.Equal(t, tt.data, logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+			assert.Equal(t, 1, observed.Len())
+		})
+	}
+}
+
+func TestAsTraceID(t *testing.T) {
+	traceID, ok := asTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	assert.True(t, ok)
+	assert.Equal(t, pcommon.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}, traceID)
+
+	for _, operationID := range []string{"", "|4bf92f35.", "00000000000000000000000000000000", "zzf92f3577b34da6a3ce929d0e0e4736"} {
+		_, ok = asTraceID(operationID)
+		assert.False(t, ok, operationID)
+	}
+}
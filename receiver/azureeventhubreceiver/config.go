@@ -17,6 +17,8 @@ package azureeventhubreceiver // import "github.com/open-telemetry/opentelemetry
 import (
 	"errors"
 	"fmt"
+	"time"
+	"unicode"
 
 	"github.com/Azure/azure-amqp-common-go/v4/conn"
 	"go.opentelemetry.io/collector/component"
@@ -28,11 +30,42 @@ const (
 	defaultLogFormat logFormat = ""
 	rawLogFormat     logFormat = "raw"
 	azureLogFormat   logFormat = "azure"
+	// commonSchemaLogFormat is the Application Insights common schema.
+	commonSchemaLogFormat logFormat = "azure-common-schema"
+)
+
+type bodyEncoding string
+
+const (
+	defaultBodyEncoding bodyEncoding = ""
+	bytesBodyEncoding   bodyEncoding = "bytes"
+	stringBodyEncoding  bodyEncoding = "string"
+)
+
+// parseErrorMode is how events that cannot be parsed are handled.
+type parseErrorMode string
+
+const (
+	defaultOnParseError parseErrorMode = ""
+	failOnParseError    parseErrorMode = "fail"
+	rawOnParseError     parseErrorMode = "raw"
+	dropOnParseError    parseErrorMode = "drop"
+)
+
+const (
+	latestStartPosition   = "latest"
+	earliestStartPosition = "earliest"
 )
 
 var (
-	validFormats         = []logFormat{defaultLogFormat, rawLogFormat, azureLogFormat}
+	validBodyEncodings   = []bodyEncoding{defaultBodyEncoding, bytesBodyEncoding, stringBodyEncoding}
+	validParseErrorModes = []parseErrorMode{defaultOnParseError, failOnParseError, rawOnParseError, dropOnParseError}
 	errMissingConnection = errors.New("missing connection")
+	errNegativeEpoch     = errors.New("epoch must not be negative")
+	errInvalidPrefix     = errors.New("apply_properties_prefix must only contain printable characters and no whitespace")
+	errNegativeLagPoll   = errors.New("lag_poll_interval must not be negative")
+	errInvalidStartFrom  = errors.New(`start_from must be "latest", "earliest" or an RFC 3339 enqueued time`)
+	errNegativeMaxSize   = errors.New("max_event_size must not be negative")
 )
 
 type Config struct {
@@ -41,17 +74,61 @@ type Config struct {
 	Offset     string        `mapstructure:"offset"`
 	StorageID  *component.ID `mapstructure:"storage"`
 	Format     string        `mapstructure:"format"`
+	// Decompression is how event data is inflated before it is translated: "none" (default) keeps it as-is,
+	// "gzip" always inflates it, and "auto" only inflates data starting with the gzip magic bytes.
+	Decompression string `mapstructure:"decompression"`
+	// BodyEncoding is how the raw format stores event data in the log record body: "bytes" (default)
+	// or "string". The content type of an event takes precedence.
+	BodyEncoding string `mapstructure:"body_encoding"`
+	// Epoch, when positive, receives partitions with epoch receivers, so that Event Hubs enforces a single
+	// owner per partition and consumer group, 0 disables it.
+	Epoch int64 `mapstructure:"epoch"`
+	// ApplyPropertiesPrefix is prepended to the keys of the event properties set as attributes by the raw format.
+	ApplyPropertiesPrefix string `mapstructure:"apply_properties_prefix"`
+	// LagPollInterval is how often the lag of each partition is reported, 0 disables it.
+	LagPollInterval time.Duration `mapstructure:"lag_poll_interval"`
+	// StartFrom is where partitions without checkpoint are received from: "latest" (default),
+	// "earliest", or an RFC 3339 enqueued time.
+	StartFrom string `mapstructure:"start_from"`
+	// MaxEventSize is the number of bytes of event data kept in the body of raw log records, 0 keeps all of it.
+	MaxEventSize int `mapstructure:"max_event_size"`
+	// OnParseError is how events that cannot be decompressed or translated are handled: "fail" (default)
+	// returns an error, "raw" keeps them in the raw format with the error as an attribute, and "drop" drops them.
+	OnParseError string `mapstructure:"on_parse_error"`
 }
 
 func isValidFormat(format string) bool {
-	for _, validFormat := range validFormats {
-		if logFormat(format) == validFormat {
+	_, ok := unmarshalers[logFormat(format)]
+	return ok
+}
+
+func isValidBodyEncoding(encoding string) bool {
+	for _, validBodyEncoding := range validBodyEncodings {
+		if bodyEncoding(encoding) == validBodyEncoding {
 			return true
 		}
 	}
 	return false
 }
 
+func isValidParseErrorMode(mode string) bool {
+	for _, validParseErrorMode := range validParseErrorModes {
+		if parseErrorMode(mode) == validParseErrorMode {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidPropertiesPrefix(prefix string) bool {
+	for _, r := range prefix {
+		if !unicode.IsPrint(r) || unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
 // Validate config
 func (config *Config) Validate() error {
 	if config.Connection == "" {
@@ -61,7 +138,31 @@ func (config *Config) Validate() error {
 		return err
 	}
 	if !isValidFormat(config.Format) {
-		return fmt.Errorf("invalid format; must be one of %#v", validFormats)
+		return fmt.Errorf("invalid format; must be one of %#v", registeredFormats())
+	}
+	if !isValidDecompression(config.Decompression) {
+		return fmt.Errorf("invalid decompression; must be one of %#v", validDecompressions)
+	}
+	if !isValidBodyEncoding(config.BodyEncoding) {
+		return fmt.Errorf("invalid body_encoding; must be one of %#v", validBodyEncodings)
+	}
+	if config.Epoch < 0 {
+		return errNegativeEpoch
+	}
+	if !isValidPropertiesPrefix(config.ApplyPropertiesPrefix) {
+		return errInvalidPrefix
+	}
+	if config.LagPollInterval < 0 {
+		return errNegativeLagPoll
+	}
+	if _, err := startOption(config.StartFrom); err != nil {
+		return errInvalidStartFrom
+	}
+	if config.MaxEventSize < 0 {
+		return errNegativeMaxSize
+	}
+	if !isValidParseErrorMode(config.OnParseError) {
+		return fmt.Errorf("invalid on_parse_error; must be one of %#v", validParseErrorModes)
 	}
 	return nil
 }
@@ -17,6 +17,7 @@ package azureeventhubreceiver // import "github.com/open-telemetry/opentelemetry
 import (
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -48,6 +49,14 @@ func TestLoadConfig(t *testing.T) {
 	assert.Equal(t, "1234-5566", r1.(*Config).Offset)
 	assert.Equal(t, "foo", r1.(*Config).Partition)
 	assert.Equal(t, rawLogFormat, logFormat(r1.(*Config).Format))
+	assert.Equal(t, autoDecompression, decompression(r1.(*Config).Decompression))
+	assert.Equal(t, stringBodyEncoding, bodyEncoding(r1.(*Config).BodyEncoding))
+	assert.Equal(t, int64(3), r1.(*Config).Epoch)
+	assert.Equal(t, "azure.eventhub.property.", r1.(*Config).ApplyPropertiesPrefix)
+	assert.Equal(t, time.Minute, r1.(*Config).LagPollInterval)
+	assert.Equal(t, 1048576, r1.(*Config).MaxEventSize)
+	assert.Equal(t, rawOnParseError, parseErrorMode(r1.(*Config).OnParseError))
+	assert.Equal(t, "earliest", r1.(*Config).StartFrom)
 }
 
 func TestMissingConnection(t *testing.T) {
@@ -80,3 +89,85 @@ func TestInvalidFormat(t *testing.T) {
 	err := component.ValidateConfig(cfg)
 	assert.ErrorContains(t, err, "invalid format; must be one of")
 }
+
+func TestInvalidDecompression(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	cfg.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+	cfg.(*Config).Decompression = "zstd"
+	err := component.ValidateConfig(cfg)
+	assert.ErrorContains(t, err, "invalid decompression; must be one of")
+}
+
+func TestInvalidBodyEncoding(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	cfg.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+	cfg.(*Config).BodyEncoding = "base64"
+	err := component.ValidateConfig(cfg)
+	assert.ErrorContains(t, err, "invalid body_encoding; must be one of")
+}
+
+func TestNegativeEpoch(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	cfg.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+	cfg.(*Config).Epoch = -1
+	err := component.ValidateConfig(cfg)
+	assert.ErrorIs(t, err, errNegativeEpoch)
+}
+
+func TestInvalidPropertiesPrefix(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	cfg.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+	cfg.(*Config).ApplyPropertiesPrefix = "azure.eventhub.property."
+	assert.NoError(t, component.ValidateConfig(cfg))
+
+	cfg.(*Config).ApplyPropertiesPrefix = "azure event hub "
+	assert.ErrorIs(t, component.ValidateConfig(cfg), errInvalidPrefix)
+}
+
+func TestNegativeLagPollInterval(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	cfg.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+	cfg.(*Config).LagPollInterval = -time.Second
+	err := component.ValidateConfig(cfg)
+	assert.ErrorIs(t, err, errNegativeLagPoll)
+}
+
+func TestInvalidStartFrom(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	cfg.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+	for _, startFrom := range []string{"latest", "earliest", "2023-02-01T10:00:00Z"} {
+		cfg.(*Config).StartFrom = startFrom
+		assert.NoError(t, component.ValidateConfig(cfg))
+	}
+
+	cfg.(*Config).StartFrom = "yesterday"
+	assert.ErrorIs(t, component.ValidateConfig(cfg), errInvalidStartFrom)
+}
+
+func TestNegativeMaxEventSize(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	cfg.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+	cfg.(*Config).MaxEventSize = -1
+	err := component.ValidateConfig(cfg)
+	assert.ErrorIs(t, err, errNegativeMaxSize)
+}
+
+func TestInvalidOnParseError(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	cfg.(*Config).Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+	for _, mode := range validParseErrorModes {
+		cfg.(*Config).OnParseError = string(mode)
+		assert.NoError(t, component.ValidateConfig(cfg))
+	}
+
+	cfg.(*Config).OnParseError = "retry"
+	assert.ErrorContains(t, component.ValidateConfig(cfg), "invalid on_parse_error")
+}
@@ -0,0 +1,82 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azureeventhubreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/azureeventhubreceiver"
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+type decompression string
+
+const (
+	defaultDecompression decompression = ""
+	noneDecompression    decompression = "none"
+	autoDecompression    decompression = "auto"
+	gzipDecompression    decompression = "gzip"
+)
+
+// maxDecompressedSize is the number of bytes event data may be inflated to, so that small
+// gzip bombs cannot exhaust memory. Inflated data is truncated to max_event_size afterwards.
+const maxDecompressedSize = 64 << 20
+
+var (
+	validDecompressions = []decompression{defaultDecompression, noneDecompression, autoDecompression, gzipDecompression}
+	gzipMagicBytes      = []byte{0x1f, 0x8b}
+)
+
+func isValidDecompression(mode string) bool {
+	for _, validDecompression := range validDecompressions {
+		if decompression(mode) == validDecompression {
+			return true
+		}
+	}
+	return false
+}
+
+// decompress inflates the event data according to the decompression mode, and fails
+// when the data inflates to more than maxSize bytes. In auto mode the data is only
+// inflated if it starts with the gzip magic bytes, and is otherwise returned as-is.
+func decompress(mode decompression, data []byte, maxSize int64) ([]byte, error) {
+	switch mode {
+	case gzipDecompression:
+		return gunzip(data, maxSize)
+	case autoDecompression:
+		if !bytes.HasPrefix(data, gzipMagicBytes) {
+			return data, nil
+		}
+		return gunzip(data, maxSize)
+	default:
+		return data, nil
+	}
+}
+
+func gunzip(data []byte, maxSize int64) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	inflated, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(inflated)) > maxSize {
+		return nil, fmt.Errorf("event data inflates to more than %d bytes", maxSize)
+	}
+	return inflated, nil
+}
@@ -0,0 +1,86 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azureeventhubreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/azureeventhubreceiver"
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestIsValidDecompression(t *testing.T) {
+	for _, mode := range validDecompressions {
+		assert.True(t, isValidDecompression(string(mode)))
+	}
+	assert.False(t, isValidDecompression("zstd"))
+}
+
+func TestDecompress(t *testing.T) {
+	plain := []byte(`{"records":[]}`)
+	compressed := gzipBytes(t, plain)
+
+	tests := []struct {
+		name    string
+		mode    decompression
+		data    []byte
+		want    []byte
+		wantErr bool
+	}{
+		{name: "default_compressed", mode: defaultDecompression, data: compressed, want: compressed},
+		{name: "none_compressed", mode: noneDecompression, data: compressed, want: compressed},
+		{name: "auto_compressed", mode: autoDecompression, data: compressed, want: plain},
+		{name: "auto_plain", mode: autoDecompression, data: plain, want: plain},
+		{name: "gzip_compressed", mode: gzipDecompression, data: compressed, want: plain},
+		{name: "gzip_plain", mode: gzipDecompression, data: plain, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decompress(tt.mode, tt.data, maxDecompressedSize)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDecompressMaxSize(t *testing.T) {
+	// 64MiB of zeros compress to about 128KiB.
+	bomb := gzipBytes(t, make([]byte, 64<<20))
+
+	_, err := decompress(gzipDecompression, bomb, 1<<20)
+	assert.EqualError(t, err, "event data inflates to more than 1048576 bytes")
+
+	got, err := decompress(autoDecompression, bomb, 64<<20)
+	require.NoError(t, err)
+	assert.Len(t, got, 64<<20)
+
+	_, err = decompress(autoDecompression, bomb, 64<<20-1)
+	assert.Error(t, err)
+}
@@ -16,7 +16,9 @@ package azureeventhubreceiver // import "github.com/open-telemetry/opentelemetry
 
 import (
 	"context"
+	"fmt"
 
+	"go.opencensus.io/stats/view"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/obsreport"
@@ -32,6 +34,8 @@ const (
 
 // NewFactory creates a factory for the Azure Event Hub receiver.
 func NewFactory() receiver.Factory {
+	_ = view.Register(MetricViews()...)
+
 	return receiver.NewFactory(
 		typeStr,
 		createDefaultConfig,
@@ -53,21 +57,16 @@ func createLogsReceiver(_ context.Context, settings receiver.CreateSettings, cfg
 		return nil, err
 	}
 
-	var converter eventConverter
-	switch logFormat(cfg.(*Config).Format) {
-	case azureLogFormat:
-		converter = newAzureLogFormatConverter(settings)
-	case rawLogFormat:
-		converter = newRawConverter(settings)
-	default:
-		converter = newAzureLogFormatConverter(settings)
+	newUnmarshaler, ok := unmarshalers[logFormat(cfg.(*Config).Format)]
+	if !ok {
+		return nil, fmt.Errorf("no unmarshaler registered for format %q", cfg.(*Config).Format)
 	}
 
 	return &client{
-		settings: settings,
-		consumer: logs,
-		config:   cfg.(*Config),
-		obsrecv:  obsrecv,
-		convert:  converter,
+		settings:    settings,
+		consumer:    logs,
+		config:      cfg.(*Config),
+		obsrecv:     obsrecv,
+		unmarshaler: newUnmarshaler(settings, cfg.(*Config)),
 	}, nil
 }
@@ -0,0 +1,55 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azureeventhubreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/azureeventhubreceiver"
+
+import (
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+var (
+	tagInstanceName, _ = tag.NewKey("name")
+	tagPartition, _    = tag.NewKey("partition")
+
+	statParseFailures = stats.Int64("azureeventhub_receiver_parse_failures", "Number of events that could not be translated to logs", stats.UnitDimensionless)
+	statPartitionLag  = stats.Int64("azureeventhub_receiver_partition_lag", "Number of events enqueued in a partition after the last received event", stats.UnitDimensionless)
+)
+
+// MetricViews return metric views for the Azure Event Hub receiver.
+func MetricViews() []*view.View {
+	tagKeys := []tag.Key{tagInstanceName}
+
+	countParseFailures := &view.View{
+		Name:        statParseFailures.Name(),
+		Measure:     statParseFailures,
+		Description: statParseFailures.Description(),
+		TagKeys:     tagKeys,
+		Aggregation: view.Sum(),
+	}
+
+	lastValuePartitionLag := &view.View{
+		Name:        statPartitionLag.Name(),
+		Measure:     statPartitionLag,
+		Description: statPartitionLag.Description(),
+		TagKeys:     []tag.Key{tagInstanceName, tagPartition},
+		Aggregation: view.LastValue(),
+	}
+
+	return []*view.View{
+		countParseFailures,
+		lastValuePartitionLag,
+	}
+}
@@ -51,3 +51,9 @@ func (s *storageCheckpointPersister) Read(namespace, name, consumerGroup, partit
 	err = jsoniter.Unmarshal(bytes, &checkpoint)
 	return checkpoint, err
 }
+
+// hasCheckpoint reports whether a checkpoint was stored for the partition.
+func (s *storageCheckpointPersister) hasCheckpoint(namespace, name, consumerGroup, partitionID string) (bool, error) {
+	bytes, err := s.storageClient.Get(context.Background(), fmt.Sprintf(storageKeyFormat, namespace, name, consumerGroup, partitionID))
+	return len(bytes) > 0, err
+}
@@ -15,28 +15,112 @@
 package azureeventhubreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/azureeventhubreceiver"
 
 import (
+	"mime"
+	"strings"
+	"unicode/utf8"
+
 	eventhub "github.com/Azure/azure-event-hubs-go/v3"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/receiver"
+	"go.uber.org/zap"
 )
 
-type rawConverter struct{}
+// contentTypeProperty is the application property holding the content type of
+// events sent by clients that do not set the AMQP content type.
+const contentTypeProperty = "content-type"
+
+// Attributes of the log records whose body holds truncated event data.
+const truncatedAttribute = "azure.eventhub.truncated"
+const originalSizeAttribute = "azure.eventhub.original_size"
 
-func newRawConverter(_ receiver.CreateSettings) *rawConverter {
-	return &rawConverter{}
+type rawConverter struct {
+	logger           *zap.Logger
+	bodyEncoding     bodyEncoding
+	propertiesPrefix string
+	maxEventSize     int
+}
+
+func newRawConverter(settings receiver.CreateSettings, config *Config) *rawConverter {
+	return &rawConverter{
+		logger:           settings.Logger,
+		bodyEncoding:     bodyEncoding(config.BodyEncoding),
+		propertiesPrefix: config.ApplyPropertiesPrefix,
+		maxEventSize:     config.MaxEventSize,
+	}
 }
 
-func (*rawConverter) ToLogs(event *eventhub.Event) (plog.Logs, error) {
+func (c *rawConverter) ToLogs(event *eventhub.Event) (plog.Logs, error) {
 	l := plog.NewLogs()
 	lr := l.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
-	slice := lr.Body().SetEmptyBytes()
-	slice.Append(event.Data...)
+	data, encoding := event.Data, c.eventBodyEncoding(event)
+	truncated := c.maxEventSize > 0 && len(data) > c.maxEventSize
+	if truncated {
+		data = truncateData(data, c.maxEventSize, encoding)
+	}
+	c.setBody(lr.Body(), data, encoding)
 	if event.SystemProperties.EnqueuedTime != nil {
 		lr.SetTimestamp(pcommon.NewTimestampFromTime(*event.SystemProperties.EnqueuedTime))
 	}
-	if err := lr.Attributes().FromRaw(event.Properties); err != nil {
-		return l, err
+	if c.propertiesPrefix == "" {
+		if err := lr.Attributes().FromRaw(event.Properties); err != nil {
+			return l, err
+		}
+	} else {
+		for key, value := range event.Properties {
+			if err := lr.Attributes().PutEmpty(c.propertiesPrefix + key).FromRaw(value); err != nil {
+				return l, err
+			}
+		}
+	}
+	if truncated {
+		lr.Attributes().PutBool(truncatedAttribute, true)
+		lr.Attributes().PutInt(originalSizeAttribute, int64(len(event.Data)))
 	}
 	return l, nil
 }
+
+// truncateData returns the first maxSize bytes of data. Data kept as a string body
+// is cut before the first incomplete UTF-8 character instead, so it stays valid.
+func truncateData(data []byte, maxSize int, encoding bodyEncoding) []byte {
+	if encoding == stringBodyEncoding && utf8.Valid(data) {
+		for maxSize > 0 && !utf8.RuneStart(data[maxSize]) {
+			maxSize--
+		}
+	}
+	return data[:maxSize]
+}
+
+// eventBodyEncoding returns the body encoding hinted by the content type of the event:
+// string for JSON and text media types, and bytes for application/octet-stream. The
+// configured encoding is used for events without a recognized content type.
+func (c *rawConverter) eventBodyEncoding(event *eventhub.Event) bodyEncoding {
+	contentType := event.RawAMQPMessage.Properties.ContentType
+	if contentType == "" {
+		contentType, _ = event.Properties[contentTypeProperty].(string)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return c.bodyEncoding
+	}
+	switch {
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"), strings.HasPrefix(mediaType, "text/"):
+		return stringBodyEncoding
+	case mediaType == "application/octet-stream":
+		return bytesBodyEncoding
+	}
+	return c.bodyEncoding
+}
+
+// setBody sets the event data as a string body in string encoding mode, and as
+// a bytes body otherwise. Data that is not valid UTF-8 is always kept as bytes.
+func (c *rawConverter) setBody(body pcommon.Value, data []byte, encoding bodyEncoding) {
+	if encoding == stringBodyEncoding {
+		if utf8.Valid(data) {
+			body.SetStr(string(data))
+			return
+		}
+		c.logger.Debug("Event data is not valid UTF-8, keeping it as a bytes body")
+	}
+	body.SetEmptyBytes().FromRaw(data)
+}
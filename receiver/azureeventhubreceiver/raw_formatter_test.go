@@ -0,0 +1,278 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azureeventhubreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/azureeventhubreceiver"
+
+import (
+	"testing"
+	"time"
+
+	eventhub "github.com/Azure/azure-event-hubs-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+)
+
+func TestRawConverter_ToLogs(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		encoding bodyEncoding
+		data     []byte
+		wantType pcommon.ValueType
+	}{
+		{
+			name:     "default",
+			encoding: defaultBodyEncoding,
+			data:     []byte(`{"message":"hello"}`),
+			wantType: pcommon.ValueTypeBytes,
+		},
+		{
+			name:     "bytes",
+			encoding: bytesBodyEncoding,
+			data:     []byte(`{"message":"hello"}`),
+			wantType: pcommon.ValueTypeBytes,
+		},
+		{
+			name:     "string",
+			encoding: stringBodyEncoding,
+			data:     []byte(`{"message":"hello"}`),
+			wantType: pcommon.ValueTypeStr,
+		},
+		{
+			name:     "string_invalid_utf8",
+			encoding: stringBodyEncoding,
+			data:     []byte{0xff, 0xfe, 0xfd},
+			wantType: pcommon.ValueTypeBytes,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := newRawConverter(receivertest.NewNopCreateSettings(), &Config{BodyEncoding: string(tt.encoding)})
+			logs, err := converter.ToLogs(&eventhub.Event{
+				Data:       tt.data,
+				Properties: map[string]interface{}{"foo": "bar"},
+				SystemProperties: &eventhub.SystemProperties{
+					EnqueuedTime: &now,
+				},
+			})
+			require.NoError(t, err)
+			require.Equal(t, 1, logs.LogRecordCount())
+
+			lr := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+			assert.Equal(t, tt.wantType, lr.Body().Type())
+			if tt.wantType == pcommon.ValueTypeStr {
+				assert.Equal(t, string(tt.data), lr.Body().Str())
+			} else {
+				assert.Equal(t, tt.data, lr.Body().Bytes().AsRaw())
+			}
+			assert.Equal(t, pcommon.NewTimestampFromTime(now), lr.Timestamp())
+			assert.Equal(t, map[string]interface{}{"foo": "bar"}, lr.Attributes().AsRaw())
+		})
+	}
+}
+
+func TestRawConverter_propertiesPrefix(t *testing.T) {
+	event := &eventhub.Event{
+		Data:             []byte("hello"),
+		Properties:       map[string]interface{}{"service.name": "checkout", "count": int64(2)},
+		SystemProperties: &eventhub.SystemProperties{},
+	}
+	tests := []struct {
+		name   string
+		prefix string
+		want   map[string]interface{}
+	}{
+		{
+			name: "unprefixed",
+			want: map[string]interface{}{"service.name": "checkout", "count": int64(2)},
+		},
+		{
+			name:   "prefixed",
+			prefix: "azure.eventhub.property.",
+			want: map[string]interface{}{
+				"azure.eventhub.property.service.name": "checkout",
+				"azure.eventhub.property.count":        int64(2),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := newRawConverter(receivertest.NewNopCreateSettings(), &Config{ApplyPropertiesPrefix: tt.prefix})
+			logs, err := converter.ToLogs(event)
+			require.NoError(t, err)
+			lr := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+			assert.Equal(t, tt.want, lr.Attributes().AsRaw())
+		})
+	}
+}
+
+func TestRawConverter_contentType(t *testing.T) {
+	data := []byte(`{"message":"hello"}`)
+	tests := []struct {
+		name         string
+		encoding     bodyEncoding
+		amqpType     string
+		propertyType interface{}
+		wantType     pcommon.ValueType
+	}{
+		{
+			name:     "json",
+			encoding: bytesBodyEncoding,
+			amqpType: "application/json; charset=utf-8",
+			wantType: pcommon.ValueTypeStr,
+		},
+		{
+			name:     "json_suffix",
+			encoding: bytesBodyEncoding,
+			amqpType: "application/cloudevents+json",
+			wantType: pcommon.ValueTypeStr,
+		},
+		{
+			name:         "text_property",
+			encoding:     bytesBodyEncoding,
+			propertyType: "text/plain",
+			wantType:     pcommon.ValueTypeStr,
+		},
+		{
+			name:     "octet_stream",
+			encoding: stringBodyEncoding,
+			amqpType: "application/octet-stream",
+			wantType: pcommon.ValueTypeBytes,
+		},
+		{
+			name:         "amqp_type_takes_precedence",
+			encoding:     bytesBodyEncoding,
+			amqpType:     "application/json",
+			propertyType: "application/octet-stream",
+			wantType:     pcommon.ValueTypeStr,
+		},
+		{
+			name:     "unknown_type",
+			encoding: stringBodyEncoding,
+			amqpType: "application/avro",
+			wantType: pcommon.ValueTypeStr,
+		},
+		{
+			name:         "invalid_type",
+			encoding:     bytesBodyEncoding,
+			propertyType: "json",
+			wantType:     pcommon.ValueTypeBytes,
+		},
+		{
+			name:         "non_string_property",
+			encoding:     bytesBodyEncoding,
+			propertyType: int64(1),
+			wantType:     pcommon.ValueTypeBytes,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &eventhub.Event{
+				Data:             data,
+				Properties:       map[string]interface{}{},
+				SystemProperties: &eventhub.SystemProperties{},
+			}
+			event.RawAMQPMessage.Properties.ContentType = tt.amqpType
+			if tt.propertyType != nil {
+				event.Properties[contentTypeProperty] = tt.propertyType
+			}
+			converter := newRawConverter(receivertest.NewNopCreateSettings(), &Config{BodyEncoding: string(tt.encoding)})
+			logs, err := converter.ToLogs(event)
+			require.NoError(t, err)
+			lr := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+			assert.Equal(t, tt.wantType, lr.Body().Type())
+		})
+	}
+}
+
+func TestRawConverter_maxEventSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		encoding  bodyEncoding
+		data      []byte
+		wantBody  interface{}
+		truncated bool
+	}{
+		{
+			name:     "bytes_not_truncated",
+			encoding: bytesBodyEncoding,
+			data:     []byte("0123456789"),
+			wantBody: []byte("0123456789"),
+		},
+		{
+			name:      "bytes_truncated",
+			encoding:  bytesBodyEncoding,
+			data:      []byte("012345678é"),
+			wantBody:  []byte{'0', '1', '2', '3', '4', '5', '6', '7', '8', 0xc3},
+			truncated: true,
+		},
+		{
+			name:     "string_not_truncated",
+			encoding: stringBodyEncoding,
+			data:     []byte("0123456789"),
+			wantBody: "0123456789",
+		},
+		{
+			name:      "string_truncated",
+			encoding:  stringBodyEncoding,
+			data:      []byte("0123456789abc"),
+			wantBody:  "0123456789",
+			truncated: true,
+		},
+		{
+			name:      "string_truncated_at_character_boundary",
+			encoding:  stringBodyEncoding,
+			data:      []byte("012345678é"),
+			wantBody:  "012345678",
+			truncated: true,
+		},
+		{
+			name:      "string_truncated_invalid_utf8",
+			encoding:  stringBodyEncoding,
+			data:      []byte{0xff, 0xfe, 0xfd, 0xfc, 0xfb, 0xfa, 0xf9, 0xf8, 0xf7, 0xf6, 0xf5},
+			wantBody:  []byte{0xff, 0xfe, 0xfd, 0xfc, 0xfb, 0xfa, 0xf9, 0xf8, 0xf7, 0xf6},
+			truncated: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := newRawConverter(receivertest.NewNopCreateSettings(), &Config{
+				BodyEncoding:          string(tt.encoding),
+				ApplyPropertiesPrefix: "azure.eventhub.property.",
+				MaxEventSize:          10,
+			})
+			logs, err := converter.ToLogs(&eventhub.Event{
+				Data:             tt.data,
+				Properties:       map[string]interface{}{"foo": "bar"},
+				SystemProperties: &eventhub.SystemProperties{},
+			})
+			require.NoError(t, err)
+
+			lr := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+			if want, ok := tt.wantBody.(string); ok {
+				assert.Equal(t, want, lr.Body().Str())
+			} else {
+				assert.Equal(t, tt.wantBody, lr.Body().Bytes().AsRaw())
+			}
+			wantAttrs := map[string]interface{}{"azure.eventhub.property.foo": "bar"}
+			if tt.truncated {
+				wantAttrs[truncatedAttribute] = true
+				wantAttrs[originalSizeAttribute] = int64(len(tt.data))
+			}
+			assert.Equal(t, wantAttrs, lr.Attributes().AsRaw())
+		})
+	}
+}
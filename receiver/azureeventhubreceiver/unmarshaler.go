@@ -0,0 +1,70 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azureeventhubreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/azureeventhubreceiver"
+
+import (
+	"fmt"
+	"sort"
+
+	eventhub "github.com/Azure/azure-event-hubs-go/v3"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/receiver"
+)
+
+// eventUnmarshaler translates the data and properties of an event, once decompressed,
+// to logs. Each format is implemented by an unmarshaler.
+type eventUnmarshaler interface {
+	ToLogs(event *eventhub.Event) (plog.Logs, error)
+}
+
+// unmarshalerFactory creates the unmarshaler of a format for a receiver.
+type unmarshalerFactory func(settings receiver.CreateSettings, config *Config) eventUnmarshaler
+
+// unmarshalers holds the factories of the unmarshalers, keyed by the format they implement.
+// It must only be modified by registerUnmarshaler, from init functions.
+var unmarshalers = map[logFormat]unmarshalerFactory{
+	defaultLogFormat: newAzureLogFormatUnmarshaler,
+	azureLogFormat:   newAzureLogFormatUnmarshaler,
+	rawLogFormat: func(settings receiver.CreateSettings, config *Config) eventUnmarshaler {
+		return newRawConverter(settings, config)
+	},
+	commonSchemaLogFormat: func(settings receiver.CreateSettings, config *Config) eventUnmarshaler {
+		return newCommonSchemaConverter(settings, config)
+	},
+}
+
+func newAzureLogFormatUnmarshaler(settings receiver.CreateSettings, _ *Config) eventUnmarshaler {
+	return newAzureLogFormatConverter(settings)
+}
+
+// registerUnmarshaler makes format a valid format of the configuration, implemented by
+// the unmarshalers created by factory. It fails if the format is already registered.
+func registerUnmarshaler(format logFormat, factory unmarshalerFactory) error {
+	if _, ok := unmarshalers[format]; ok {
+		return fmt.Errorf("an unmarshaler is already registered for format %q", format)
+	}
+	unmarshalers[format] = factory
+	return nil
+}
+
+// registeredFormats returns the formats having an unmarshaler, in alphabetical order.
+func registeredFormats() []logFormat {
+	formats := make([]logFormat, 0, len(unmarshalers))
+	for format := range unmarshalers {
+		formats = append(formats, format)
+	}
+	sort.Slice(formats, func(i, j int) bool { return formats[i] < formats[j] })
+	return formats
+}
@@ -0,0 +1,89 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azureeventhubreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/azureeventhubreceiver"
+
+import (
+	"context"
+	"testing"
+
+	eventhub "github.com/Azure/azure-event-hubs-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/receiver"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+)
+
+type framedUnmarshaler struct{}
+
+func (framedUnmarshaler) ToLogs(event *eventhub.Event) (plog.Logs, error) {
+	l := plog.NewLogs()
+	lr := l.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	lr.Body().SetStr(string(event.Data[1:]))
+	return l, nil
+}
+
+func TestBuiltInUnmarshalers(t *testing.T) {
+	settings := receivertest.NewNopCreateSettings()
+	tests := []struct {
+		format logFormat
+		want   eventUnmarshaler
+	}{
+		{format: defaultLogFormat, want: &azureLogFormatConverter{}},
+		{format: azureLogFormat, want: &azureLogFormatConverter{}},
+		{format: rawLogFormat, want: &rawConverter{}},
+		{format: commonSchemaLogFormat, want: &commonSchemaConverter{}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			newUnmarshaler, ok := unmarshalers[tt.format]
+			require.True(t, ok)
+			assert.IsType(t, tt.want, newUnmarshaler(settings, &Config{Format: string(tt.format)}))
+		})
+	}
+}
+
+func TestRegisterUnmarshaler(t *testing.T) {
+	const framedLogFormat logFormat = "framed"
+	require.NoError(t, registerUnmarshaler(framedLogFormat, func(receiver.CreateSettings, *Config) eventUnmarshaler {
+		return framedUnmarshaler{}
+	}))
+	defer delete(unmarshalers, framedLogFormat)
+	assert.Error(t, registerUnmarshaler(framedLogFormat, nil))
+	assert.Error(t, registerUnmarshaler(rawLogFormat, nil))
+	assert.Equal(t, []logFormat{defaultLogFormat, azureLogFormat, commonSchemaLogFormat, framedLogFormat, rawLogFormat}, registeredFormats())
+
+	// The format of a registered unmarshaler is a valid format.
+	f := NewFactory()
+	cfg := f.CreateDefaultConfig().(*Config)
+	cfg.Connection = "Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=superSecret1234=;EntityPath=hubName"
+	cfg.Format = string(framedLogFormat)
+	require.NoError(t, cfg.Validate())
+
+	rcv, err := f.CreateLogsReceiver(context.Background(), receivertest.NewNopCreateSettings(), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	logs, err := rcv.(*client).unmarshaler.ToLogs(&eventhub.Event{Data: []byte{0x01, 'h', 'i'}})
+	require.NoError(t, err)
+	assert.Equal(t, "hi", logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+}
+
+func TestCreateLogsReceiverUnknownFormat(t *testing.T) {
+	f := NewFactory()
+	cfg := f.CreateDefaultConfig().(*Config)
+	cfg.Format = "unknown"
+	_, err := f.CreateLogsReceiver(context.Background(), receivertest.NewNopCreateSettings(), cfg, consumertest.NewNop())
+	assert.ErrorContains(t, err, `no unmarshaler registered for format "unknown"`)
+}
@@ -0,0 +1,115 @@
+// Copyright 2020, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhecreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/splunkhecreceiver"
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+// logsCoalescer accumulates the logs of several requests and passes them to the
+// next consumer in a single call, once maxRecords log records are pending or
+// timeout elapsed since the first pending record was added. Logs are sent in the
+// background, so failures of the next consumer are logged instead of being
+// reported to the clients. Only one send is in flight at a time: logs due to be
+// sent while the previous ones are still being sent block add, which pushes back
+// on the requests instead of piling up logs when the next consumer is slow.
+type logsCoalescer struct {
+	next       consumer.Logs
+	logger     *zap.Logger
+	health     *downstreamHealth
+	maxRecords int
+	timeout    time.Duration
+
+	mu       sync.Mutex
+	pending  plog.Logs
+	records  int
+	timer    *time.Timer
+	inFlight sync.WaitGroup
+	// sending holds a token while logs are being sent.
+	sending chan struct{}
+}
+
+func newLogsCoalescer(next consumer.Logs, logger *zap.Logger, health *downstreamHealth, cfg CoalesceConfig) *logsCoalescer {
+	return &logsCoalescer{
+		next:       next,
+		logger:     logger,
+		health:     health,
+		maxRecords: cfg.MaxRecords,
+		timeout:    cfg.Timeout,
+		pending:    plog.NewLogs(),
+		sending:    make(chan struct{}, 1),
+	}
+}
+
+// add moves the resource logs of ld to the pending logs.
+func (c *logsCoalescer) add(ld plog.Logs) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.records += ld.LogRecordCount()
+	ld.ResourceLogs().MoveAndAppendTo(c.pending.ResourceLogs())
+	if c.records >= c.maxRecords {
+		c.sendPending()
+		return
+	}
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.timeout, c.flush)
+	}
+}
+
+// flush sends the pending logs, if any.
+func (c *logsCoalescer) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sendPending()
+}
+
+// shutdown sends the pending logs and waits for all sends to complete.
+func (c *logsCoalescer) shutdown() {
+	c.flush()
+	c.inFlight.Wait()
+}
+
+// sendPending sends the pending logs in the background, once the previous send completed.
+// It must be called with c.mu held, so adding logs is blocked until then.
+func (c *logsCoalescer) sendPending() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if c.records == 0 {
+		return
+	}
+	ld := c.pending
+	c.pending = plog.NewLogs()
+	c.records = 0
+
+	c.sending <- struct{}{}
+	c.inFlight.Add(1)
+	go func() {
+		defer c.inFlight.Done()
+		defer func() { <-c.sending }()
+		err := c.next.ConsumeLogs(context.Background(), ld)
+		c.health.record(err)
+		if err != nil {
+			c.logger.Error("Failed to send coalesced logs", zap.Int("records", ld.LogRecordCount()), zap.Error(err))
+		}
+	}()
+}
@@ -0,0 +1,119 @@
+// Copyright 2020, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhecreceiver
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+func newTestLogs(numRecords int) plog.Logs {
+	ld := plog.NewLogs()
+	sl := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty()
+	for i := 0; i < numRecords; i++ {
+		sl.LogRecords().AppendEmpty().Body().SetInt(int64(i))
+	}
+	return ld
+}
+
+func TestLogsCoalescerFlushOnTimeout(t *testing.T) {
+	sink := new(consumertest.LogsSink)
+	c := newLogsCoalescer(sink, zap.NewNop(), newDownstreamHealth(0, 0), CoalesceConfig{MaxRecords: 100, Timeout: 50 * time.Millisecond})
+
+	c.add(newTestLogs(2))
+	c.add(newTestLogs(3))
+	assert.Len(t, sink.AllLogs(), 0, "logs are accumulated until the timeout")
+
+	assert.Eventually(t, func() bool { return len(sink.AllLogs()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 5, sink.LogRecordCount())
+	assert.Equal(t, 2, sink.AllLogs()[0].ResourceLogs().Len())
+
+	c.add(newTestLogs(1))
+	assert.Eventually(t, func() bool { return len(sink.AllLogs()) == 2 }, time.Second, 10*time.Millisecond)
+	c.shutdown()
+	assert.Equal(t, 6, sink.LogRecordCount())
+}
+
+func TestLogsCoalescerFlushOnMaxRecords(t *testing.T) {
+	sink := new(consumertest.LogsSink)
+	c := newLogsCoalescer(sink, zap.NewNop(), newDownstreamHealth(0, 0), CoalesceConfig{MaxRecords: 4, Timeout: time.Hour})
+
+	c.add(newTestLogs(3))
+	c.add(newTestLogs(2))
+	assert.Eventually(t, func() bool { return len(sink.AllLogs()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 5, sink.LogRecordCount())
+
+	c.add(newTestLogs(1))
+	c.shutdown()
+	assert.Len(t, sink.AllLogs(), 2, "pending logs are sent on shutdown")
+	assert.Equal(t, 6, sink.LogRecordCount())
+}
+
+func TestLogsCoalescerRecordsHealth(t *testing.T) {
+	health := newDownstreamHealth(1, time.Minute)
+	c := newLogsCoalescer(consumertest.NewErr(errors.New("consumer failed")), zap.NewNop(), health, CoalesceConfig{MaxRecords: 1, Timeout: time.Hour})
+
+	c.add(newTestLogs(1))
+	c.shutdown()
+	assert.False(t, health.healthy())
+}
+
+func TestLogsCoalescerOneSendInFlight(t *testing.T) {
+	release := make(chan struct{})
+	var calls, inFlight, maxInFlight atomic.Int64
+	next, err := consumer.NewLogs(func(context.Context, plog.Logs) error {
+		calls.Add(1)
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		if n > maxInFlight.Load() {
+			maxInFlight.Store(n)
+		}
+		<-release
+		return nil
+	})
+	require.NoError(t, err)
+	c := newLogsCoalescer(next, zap.NewNop(), newDownstreamHealth(0, 0), CoalesceConfig{MaxRecords: 1, Timeout: time.Hour})
+
+	c.add(newTestLogs(1))
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	// The next logs are due to be sent while the first ones are still being sent.
+	added := make(chan struct{})
+	go func() {
+		c.add(newTestLogs(1))
+		close(added)
+	}()
+	select {
+	case <-added:
+		t.Fatal("add returned while the previous logs were still being sent")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-added
+	c.shutdown()
+	assert.Equal(t, int64(2), calls.Load())
+	assert.Equal(t, int64(1), maxInFlight.Load())
+}
@@ -15,6 +15,12 @@
 package splunkhecreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/splunkhecreceiver"
 
 import (
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
 	"go.opentelemetry.io/collector/config/confighttp"
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/splunk"
@@ -25,10 +31,305 @@ type Config struct {
 	confighttp.HTTPServerSettings `mapstructure:",squash"` // squash ensures fields are correctly decoded in embedded struct
 
 	splunk.AccessTokenPassthroughConfig `mapstructure:",squash"`
+	// Tokens maps HEC tokens to the resource attributes set on the data of the requests bearing them,
+	// such as a tenant identifier, so the data can be routed without holding the token itself.
+	// Requests without a token, or with a token that is not listed, are handled as before.
+	Tokens map[string]map[string]string `mapstructure:"tokens"`
 	// RawPath for raw data collection, default is '/services/collector/raw'
 	RawPath string `mapstructure:"raw_path"`
 	// HealthPath for health API, default is '/services/collector/health'
 	HealthPath string `mapstructure:"health_path"`
 	// HecToOtelAttrs creates a mapping from HEC metadata to attributes.
 	HecToOtelAttrs splunk.HecToOtelAttrs `mapstructure:"hec_metadata_to_otel_attrs"`
+	// BodyField selects the part of the HEC event used as the log body, default is 'event'.
+	// A nested value can be selected with 'event.<key>[.<key>...]' or 'fields.<key>'.
+	BodyField string `mapstructure:"body_field"`
+	// SplitByIndex groups log events by their index only, so each index gets its own resource.
+	// The host, source and sourcetype of the events are then set as log record attributes.
+	SplitByIndex bool `mapstructure:"split_by_index"`
+	// SeverityField selects the part of the HEC event holding the log level, using the same
+	// syntax as BodyField. Severity is left unset when empty or when the field is absent.
+	SeverityField string `mapstructure:"severity_field"`
+	// TimeNanosField selects the part of the HEC event holding a nanosecond-precision timestamp, as
+	// nanoseconds since the epoch or an RFC 3339 string, using the same syntax as BodyField. When the
+	// field is present, it overrides the HEC event time and the time_field of sourcetype overrides.
+	TimeNanosField string `mapstructure:"time_nanos_field"`
+	// DrainTimeout is how long Shutdown waits for in-flight requests to complete before
+	// closing their connections, default is 10s. Zero means waiting as long as the shutdown context allows.
+	DrainTimeout time.Duration `mapstructure:"drain_timeout"`
+	// AllowedContentTypes lists the media types accepted on the event endpoint, default is 'application/json'.
+	// Text media types listed here, such as 'text/plain', are read as newline-delimited raw log lines.
+	AllowedContentTypes []string `mapstructure:"allowed_content_types"`
+	// KeepRawEvent stores the JSON of each log event, as sent by the client, in the RawEventAttribute attribute.
+	KeepRawEvent bool `mapstructure:"keep_raw_event"`
+	// RawEventAttribute is the log record attribute holding the raw event when KeepRawEvent is enabled, default is 'splunk.raw'.
+	RawEventAttribute string `mapstructure:"raw_event_attribute"`
+	// FailureThreshold is the number of consecutive failures of the next consumer after which the receiver
+	// reports itself unhealthy and rejects requests with 503 for RecoveryWindow. Zero, the default, disables it.
+	FailureThreshold int `mapstructure:"failure_threshold"`
+	// RecoveryWindow is how long the receiver stays unhealthy once FailureThreshold is reached, default is 30s.
+	RecoveryWindow time.Duration `mapstructure:"recovery_window"`
+	// ChannelPassthrough copies the channel of requests, set by the X-Splunk-Request-Channel header or the
+	// "channel" query parameter, to the ChannelAttribute resource attribute.
+	ChannelPassthrough bool `mapstructure:"channel_passthrough"`
+	// ChannelAttribute is the resource attribute holding the channel when ChannelPassthrough is enabled,
+	// default is 'com.splunk.hec.channel'.
+	ChannelAttribute string `mapstructure:"channel_attribute"`
+	// Responses overrides the text of the responses to requests, keyed by "success" or by failure
+	// category. The HTTP status and Splunk HEC code of the responses are not changed.
+	Responses map[string]string `mapstructure:"responses"`
+	// FlattenBody puts the values of HEC events holding a JSON object as log record attributes,
+	// with the keys of nested objects joined by dots.
+	FlattenBody bool `mapstructure:"flatten_body"`
+	// FlattenMaxDepth is the number of nested object levels flattened by FlattenBody, default is 5.
+	// Objects nested deeper are kept as map attributes.
+	FlattenMaxDepth int `mapstructure:"flatten_max_depth"`
+	// SourcetypeOverrides overrides the fields used to build the log records of events, keyed by sourcetype.
+	SourcetypeOverrides map[string]SourcetypeOverride `mapstructure:"sourcetype_overrides"`
+	// EnableH2C accepts cleartext HTTP/2 (h2c) connections. HTTP/2 is always accepted when TLS is configured.
+	EnableH2C bool `mapstructure:"enable_h2c"`
+	// Coalesce accumulates log events across requests before passing them to the next consumer.
+	Coalesce CoalesceConfig `mapstructure:"coalesce"`
+	// Dedup drops log events whose key was already seen within a time window.
+	Dedup DedupConfig `mapstructure:"dedup"`
+	// DropMetricEvents drops the metric events sent to a logs receiver instead of rejecting their request.
+	DropMetricEvents bool `mapstructure:"drop_metric_events"`
+	// DropEmptyEvents drops the log events whose body, selected by BodyField, is empty or only holds whitespace.
+	DropEmptyEvents bool `mapstructure:"drop_empty_events"`
+	// HostAsLogAttribute sets the host of log events as a log record attribute instead of a resource
+	// attribute, so events are not grouped by host. The attribute name is set by HecToOtelAttrs.Host.
+	HostAsLogAttribute bool `mapstructure:"host_as_log_attribute"`
+	// EventKey is the key of the HEC event object holding the log payload, default is 'event'.
+	// The other keys that are not HEC event keys are then set as fields, and the whole object
+	// is used as the event when the key is absent.
+	EventKey string `mapstructure:"event_key"`
+	// LogFailedRequests logs a snippet of the body of requests whose events cannot be decoded, around
+	// the position at which decoding failed. Request bodies are then buffered before being decoded.
+	LogFailedRequests bool `mapstructure:"log_failed_requests"`
+	// FailedRequestSnippetSize is the maximum number of bytes of the body logged by LogFailedRequests, default is 1024.
+	FailedRequestSnippetSize int `mapstructure:"failed_request_snippet_size"`
+	// RedactedFields are the names of the JSON fields whose values are replaced in the logged snippets.
+	RedactedFields []string `mapstructure:"redacted_fields"`
+	// ParallelDecode decodes the events of large requests with a pool of workers.
+	ParallelDecode ParallelDecodeConfig `mapstructure:"parallel_decode"`
+	// DefaultHost is the host of raw log lines sent without the "host" query parameter.
+	DefaultHost string `mapstructure:"default_host"`
+	// DefaultSource is the source of raw log lines sent without the "source" query parameter.
+	DefaultSource string `mapstructure:"default_source"`
+	// DefaultSourcetype is the sourcetype of raw log lines sent without the "sourcetype" query parameter.
+	DefaultSourcetype string `mapstructure:"default_sourcetype"`
+	// DefaultIndex is the index of raw log lines sent without the "index" query parameter.
+	DefaultIndex string `mapstructure:"default_index"`
+	// ReusePort binds the endpoint with SO_REUSEPORT, so that several receivers, in the same process
+	// or in several collector processes, can listen on the same port. It is only supported on Linux.
+	ReusePort bool `mapstructure:"reuse_port"`
+	// MaxDecompressionRatio rejects gzip bodies inflating to more than this many bytes per compressed
+	// byte read, once they are decompressed past 1MiB. Zero, the default, disables the limit.
+	MaxDecompressionRatio int `mapstructure:"max_decompression_ratio"`
+	// EnableDebugStats serves the number of requests, events, decode errors and consumer errors
+	// handled by the receiver as JSON at /debug/hec-stats.
+	EnableDebugStats bool `mapstructure:"enable_debug_stats"`
+}
+
+// CoalesceConfig defines how log events are accumulated across requests.
+type CoalesceConfig struct {
+	// Enabled accumulates log events and acknowledges requests before the events are passed to the next consumer.
+	Enabled bool `mapstructure:"enabled"`
+	// MaxRecords is the number of accumulated log records after which they are sent, default is 8192.
+	MaxRecords int `mapstructure:"max_records"`
+	// Timeout is how long log records are accumulated before being sent, default is 200ms.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// DedupConfig defines how repeated log events are dropped.
+type DedupConfig struct {
+	// Enabled drops log events whose key was already seen within the TTL.
+	Enabled bool `mapstructure:"enabled"`
+	// KeyField selects the part of the HEC event identifying it, using the same syntax as BodyField,
+	// default is 'fields.id'. Events without the field are never dropped.
+	KeyField string `mapstructure:"key_field"`
+	// CacheSize is the number of keys remembered, default is 10000.
+	CacheSize int `mapstructure:"cache_size"`
+	// TTL is how long a key is remembered after it was first seen, default is 5m.
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+// ParallelDecodeConfig defines how the events of large requests are decoded concurrently.
+type ParallelDecodeConfig struct {
+	// Enabled decodes the events of requests of at least MinContentLength bytes with Workers workers.
+	Enabled bool `mapstructure:"enabled"`
+	// Workers is the number of workers decoding the events of a request, default is 4.
+	Workers int `mapstructure:"workers"`
+	// MinContentLength is the size of the events read from a request, once decompressed, from
+	// which they are decoded concurrently, default is 1MiB. Smaller requests are not worth the overhead.
+	MinContentLength int64 `mapstructure:"min_content_length"`
+}
+
+// SourcetypeOverride defines the fields used to build the log records of the events of a sourcetype.
+// Fields left empty fall back to the receiver settings.
+type SourcetypeOverride struct {
+	// BodyField overrides the body_field setting.
+	BodyField string `mapstructure:"body_field"`
+	// TimeField selects the part of the HEC event holding the timestamp in seconds since the epoch,
+	// using the same syntax as BodyField. The HEC event time is used when it is empty or the field is absent.
+	TimeField string `mapstructure:"time_field"`
+	// SeverityField overrides the severity_field setting.
+	SeverityField string `mapstructure:"severity_field"`
+}
+
+// Response categories whose text can be overridden with Config.Responses.
+const (
+	responseKeySuccess                = "success"
+	responseKeyInvalidMethod          = "invalid_method"
+	responseKeyInvalidEncoding        = "invalid_encoding"
+	responseKeyGzipReader             = "gzip_reader"
+	responseKeyUnmarshalBody          = "unmarshal_body"
+	responseKeyInternalServerError    = "internal_server_error"
+	responseKeyUnsupportedMetricEvent = "unsupported_metric_event"
+	responseKeyUnsupportedLogEvent    = "unsupported_log_event"
+	responseKeyIndexedFields          = "indexed_fields"
+	responseKeyInvalidTimeQueryParam  = "invalid_time_query_param"
+	responseKeyServerBusy             = "server_busy"
+)
+
+var responseKeys = []string{
+	responseKeySuccess,
+	responseKeyInvalidMethod,
+	responseKeyInvalidEncoding,
+	responseKeyGzipReader,
+	responseKeyUnmarshalBody,
+	responseKeyInternalServerError,
+	responseKeyUnsupportedMetricEvent,
+	responseKeyUnsupportedLogEvent,
+	responseKeyIndexedFields,
+	responseKeyInvalidTimeQueryParam,
+	responseKeyServerBusy,
+}
+
+var (
+	errInvalidBodyField           = errors.New(`"body_field" must be "event" or start with "event." or "fields."`)
+	errInvalidSeverityField       = errors.New(`"severity_field" must be "event" or start with "event." or "fields."`)
+	errInvalidTimeField           = errors.New(`"time_field" must be "event" or start with "event." or "fields."`)
+	errInvalidTimeNanosField      = errors.New(`"time_nanos_field" must be "event" or start with "event." or "fields."`)
+	errMissingRawEventAttribute   = errors.New(`"raw_event_attribute" must be set when "keep_raw_event" is enabled`)
+	errNegativeFailureThreshold   = errors.New(`"failure_threshold" must not be negative`)
+	errInvalidRecoveryWindow      = errors.New(`"recovery_window" must be positive when "failure_threshold" is set`)
+	errMissingChannelAttribute    = errors.New(`"channel_attribute" must be set when "channel_passthrough" is enabled`)
+	errInvalidCoalesceMaxRecords  = errors.New(`"coalesce::max_records" must be positive when "coalesce" is enabled`)
+	errInvalidCoalesceTimeout     = errors.New(`"coalesce::timeout" must be positive when "coalesce" is enabled`)
+	errInvalidDedupKeyField       = errors.New(`"dedup::key_field" must be "event" or start with "event." or "fields."`)
+	errInvalidDedupCacheSize      = errors.New(`"dedup::cache_size" must be positive when "dedup" is enabled`)
+	errInvalidDedupTTL            = errors.New(`"dedup::ttl" must be positive when "dedup" is enabled`)
+	errInvalidSnippetSize         = errors.New(`"failed_request_snippet_size" must be positive when "log_failed_requests" is enabled`)
+	errInvalidFlattenMaxDepth     = errors.New(`"flatten_max_depth" must be positive when "flatten_body" is enabled`)
+	errInvalidDecodeWorkers       = errors.New(`"parallel_decode::workers" must be positive when "parallel_decode" is enabled`)
+	errNegativeMinContentLength   = errors.New(`"parallel_decode::min_content_length" must not be negative`)
+	errReusePortUnsupported       = errors.New(`"reuse_port" is only supported on Linux`)
+	errNegativeDecompressionRatio = errors.New(`"max_decompression_ratio" must not be negative`)
+	errEmptyTokenAttribute        = errors.New(`"tokens" must not map a token to an empty attribute name`)
+)
+
+// Validate checks the receiver configuration is valid.
+func (c *Config) Validate() error {
+	if c.BodyField != "" && !isValidFieldPath(c.BodyField) {
+		return errInvalidBodyField
+	}
+	if c.SeverityField != "" && !isValidFieldPath(c.SeverityField) {
+		return errInvalidSeverityField
+	}
+	if c.TimeNanosField != "" && !isValidFieldPath(c.TimeNanosField) {
+		return errInvalidTimeNanosField
+	}
+	for sourcetype, override := range c.SourcetypeOverrides {
+		if err := override.validate(); err != nil {
+			return fmt.Errorf("invalid override for sourcetype %q: %w", sourcetype, err)
+		}
+	}
+	if c.LogFailedRequests && c.FailedRequestSnippetSize <= 0 {
+		return errInvalidSnippetSize
+	}
+	if c.KeepRawEvent && c.RawEventAttribute == "" {
+		return errMissingRawEventAttribute
+	}
+	if c.ChannelPassthrough && c.ChannelAttribute == "" {
+		return errMissingChannelAttribute
+	}
+	if c.FlattenBody && c.FlattenMaxDepth <= 0 {
+		return errInvalidFlattenMaxDepth
+	}
+	if c.Coalesce.Enabled && c.Coalesce.MaxRecords <= 0 {
+		return errInvalidCoalesceMaxRecords
+	}
+	if c.Coalesce.Enabled && c.Coalesce.Timeout <= 0 {
+		return errInvalidCoalesceTimeout
+	}
+	if c.Dedup.Enabled && !isValidFieldPath(c.Dedup.KeyField) {
+		return errInvalidDedupKeyField
+	}
+	if c.Dedup.Enabled && c.Dedup.CacheSize <= 0 {
+		return errInvalidDedupCacheSize
+	}
+	if c.Dedup.Enabled && c.Dedup.TTL <= 0 {
+		return errInvalidDedupTTL
+	}
+	if c.ParallelDecode.Enabled && c.ParallelDecode.Workers <= 0 {
+		return errInvalidDecodeWorkers
+	}
+	if c.ParallelDecode.MinContentLength < 0 {
+		return errNegativeMinContentLength
+	}
+	if c.MaxDecompressionRatio < 0 {
+		return errNegativeDecompressionRatio
+	}
+	if c.ReusePort && !reusePortSupported {
+		return errReusePortUnsupported
+	}
+	if c.FailureThreshold < 0 {
+		return errNegativeFailureThreshold
+	}
+	if c.FailureThreshold > 0 && c.RecoveryWindow <= 0 {
+		return errInvalidRecoveryWindow
+	}
+	for _, attributes := range c.Tokens {
+		if _, ok := attributes[""]; ok {
+			return errEmptyTokenAttribute
+		}
+	}
+	for key := range c.Responses {
+		if !isResponseKey(key) {
+			return fmt.Errorf("unknown response category %q in \"responses\"; must be one of %v", key, responseKeys)
+		}
+	}
+	for _, contentType := range c.AllowedContentTypes {
+		if _, _, err := mime.ParseMediaType(contentType); err != nil {
+			return fmt.Errorf("invalid media type %q in \"allowed_content_types\": %w", contentType, err)
+		}
+	}
+	return nil
+}
+
+func (o SourcetypeOverride) validate() error {
+	if o.BodyField != "" && !isValidFieldPath(o.BodyField) {
+		return errInvalidBodyField
+	}
+	if o.TimeField != "" && !isValidFieldPath(o.TimeField) {
+		return errInvalidTimeField
+	}
+	if o.SeverityField != "" && !isValidFieldPath(o.SeverityField) {
+		return errInvalidSeverityField
+	}
+	return nil
+}
+
+func isResponseKey(key string) bool {
+	for _, responseKey := range responseKeys {
+		if key == responseKey {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidFieldPath(path string) bool {
+	return path == eventField || strings.HasPrefix(path, eventFieldPrefix) || strings.HasPrefix(path, fieldsFieldPrefix)
 }
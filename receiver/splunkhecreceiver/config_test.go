@@ -17,6 +17,7 @@ package splunkhecreceiver
 import (
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -59,6 +60,56 @@ func TestLoadConfig(t *testing.T) {
 					Index:      "myindex",
 					Host:       "myhostfield",
 				},
+				BodyField:           "fields.message",
+				SplitByIndex:        true,
+				DrainTimeout:        5 * time.Second,
+				SeverityField:       "fields.severity",
+				TimeNanosField:      "fields.timestamp_ns",
+				AllowedContentTypes: []string{"application/json", "text/plain"},
+				KeepRawEvent:        true,
+				RawEventAttribute:   "splunk.original",
+				FailureThreshold:    5,
+				RecoveryWindow:      time.Minute,
+				ChannelPassthrough:  true,
+				ChannelAttribute:    "splunk.channel",
+				Tokens: map[string]map[string]string{
+					"00000000-0000-0000-0000-000000000001": {"tenant": "acme"},
+				},
+				Responses:       map[string]string{"internal_server_error": "Request failed"},
+				FlattenBody:     true,
+				FlattenMaxDepth: 3,
+				SourcetypeOverrides: map[string]SourcetypeOverride{
+					"nginx": {BodyField: "event.request", TimeField: "event.ts", SeverityField: "event.level"},
+				},
+				EnableH2C: true,
+				Coalesce: CoalesceConfig{
+					Enabled:    true,
+					MaxRecords: 1000,
+					Timeout:    time.Second,
+				},
+				Dedup: DedupConfig{
+					Enabled:   true,
+					KeyField:  "fields.event_id",
+					CacheSize: 500,
+					TTL:       time.Minute,
+				},
+				DropMetricEvents:         true,
+				DropEmptyEvents:          true,
+				HostAsLogAttribute:       true,
+				EventKey:                 "message",
+				LogFailedRequests:        true,
+				FailedRequestSnippetSize: 64,
+				RedactedFields:           []string{"token"},
+				ParallelDecode: ParallelDecodeConfig{
+					Enabled:          true,
+					Workers:          8,
+					MinContentLength: 65536,
+				},
+				DefaultHost:           "rawhost",
+				DefaultSource:         "rawsource",
+				DefaultSourcetype:     "rawsourcetype",
+				DefaultIndex:          "rawindex",
+				MaxDecompressionRatio: 200,
 			},
 		},
 		{
@@ -84,6 +135,28 @@ func TestLoadConfig(t *testing.T) {
 					Index:      "com.splunk.index",
 					Host:       "host.name",
 				},
+				BodyField:                "event",
+				EventKey:                 "event",
+				DrainTimeout:             10 * time.Second,
+				AllowedContentTypes:      []string{"application/json"},
+				RawEventAttribute:        "splunk.raw",
+				RecoveryWindow:           30 * time.Second,
+				ChannelAttribute:         "com.splunk.hec.channel",
+				FlattenMaxDepth:          5,
+				FailedRequestSnippetSize: 1024,
+				Coalesce: CoalesceConfig{
+					MaxRecords: 8192,
+					Timeout:    200 * time.Millisecond,
+				},
+				Dedup: DedupConfig{
+					KeyField:  "fields.id",
+					CacheSize: 10000,
+					TTL:       5 * time.Minute,
+				},
+				ParallelDecode: ParallelDecodeConfig{
+					Workers:          4,
+					MinContentLength: 1 << 20,
+				},
 			},
 		},
 	}
@@ -102,3 +175,179 @@ func TestLoadConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name           string
+		bodyField      string
+		severityField  string
+		timeNanosField string
+		wantErr        error
+	}{
+		{name: "default", bodyField: "event"},
+		{name: "empty", bodyField: ""},
+		{name: "nested_event", bodyField: "event.log.message"},
+		{name: "field", bodyField: "fields.message"},
+		{name: "invalid", bodyField: "message", wantErr: errInvalidBodyField},
+		{name: "severity_field", bodyField: "event", severityField: "fields.severity"},
+		{name: "invalid_severity_field", bodyField: "event", severityField: "severity", wantErr: errInvalidSeverityField},
+		{name: "time_nanos_field", bodyField: "event", timeNanosField: "fields.timestamp_ns"},
+		{name: "invalid_time_nanos_field", bodyField: "event", timeNanosField: "timestamp_ns", wantErr: errInvalidTimeNanosField},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			cfg.BodyField = tt.bodyField
+			cfg.SeverityField = tt.severityField
+			cfg.TimeNanosField = tt.timeNanosField
+			assert.Equal(t, tt.wantErr, cfg.Validate())
+		})
+	}
+}
+
+func TestValidateConfigAllowedContentTypes(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.AllowedContentTypes = []string{"application/json", "text/plain; charset=utf-8"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.AllowedContentTypes = []string{"text/"}
+	assert.ErrorContains(t, cfg.Validate(), `invalid media type "text/" in "allowed_content_types"`)
+}
+
+func TestValidateConfigKeepRawEvent(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.KeepRawEvent = true
+	assert.NoError(t, cfg.Validate())
+
+	cfg.RawEventAttribute = ""
+	assert.Equal(t, errMissingRawEventAttribute, cfg.Validate())
+}
+
+func TestValidateConfigFailureThreshold(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.FailureThreshold = 3
+	assert.NoError(t, cfg.Validate())
+
+	cfg.RecoveryWindow = 0
+	assert.Equal(t, errInvalidRecoveryWindow, cfg.Validate())
+
+	cfg.FailureThreshold = -1
+	assert.Equal(t, errNegativeFailureThreshold, cfg.Validate())
+}
+
+func TestValidateConfigChannelPassthrough(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.ChannelPassthrough = true
+	assert.NoError(t, cfg.Validate())
+
+	cfg.ChannelAttribute = ""
+	assert.Equal(t, errMissingChannelAttribute, cfg.Validate())
+}
+
+func TestValidateConfigResponses(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Responses = map[string]string{"success": "Accepted", "internal_server_error": "Request failed", "server_busy": "Try again later"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Responses = map[string]string{"internal_error": "Request failed"}
+	assert.ErrorContains(t, cfg.Validate(), `unknown response category "internal_error" in "responses"`)
+}
+
+func TestValidateConfigSourcetypeOverrides(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.SourcetypeOverrides = map[string]SourcetypeOverride{
+		"nginx": {BodyField: "event.request", TimeField: "event.ts", SeverityField: "fields.level"},
+		"app":   {TimeField: "fields.ts"},
+	}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.SourcetypeOverrides["app"] = SourcetypeOverride{TimeField: "ts"}
+	err := cfg.Validate()
+	assert.ErrorIs(t, err, errInvalidTimeField)
+	assert.ErrorContains(t, err, `invalid override for sourcetype "app"`)
+}
+
+func TestValidateConfigFlattenBody(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.FlattenBody = true
+	assert.NoError(t, cfg.Validate())
+
+	cfg.FlattenMaxDepth = 0
+	assert.Equal(t, errInvalidFlattenMaxDepth, cfg.Validate())
+}
+
+func TestValidateConfigCoalesce(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Coalesce.Enabled = true
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Coalesce.Timeout = 0
+	assert.Equal(t, errInvalidCoalesceTimeout, cfg.Validate())
+
+	cfg.Coalesce.MaxRecords = 0
+	assert.Equal(t, errInvalidCoalesceMaxRecords, cfg.Validate())
+}
+
+func TestValidateConfigParallelDecode(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.ParallelDecode.Enabled = true
+	assert.NoError(t, cfg.Validate())
+
+	cfg.ParallelDecode.MinContentLength = -1
+	assert.Equal(t, errNegativeMinContentLength, cfg.Validate())
+
+	cfg.ParallelDecode.Workers = 0
+	assert.Equal(t, errInvalidDecodeWorkers, cfg.Validate())
+}
+
+func TestValidateConfigDedup(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Dedup.Enabled = true
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Dedup.TTL = 0
+	assert.Equal(t, errInvalidDedupTTL, cfg.Validate())
+
+	cfg.Dedup.CacheSize = 0
+	assert.Equal(t, errInvalidDedupCacheSize, cfg.Validate())
+
+	cfg.Dedup.KeyField = "id"
+	assert.Equal(t, errInvalidDedupKeyField, cfg.Validate())
+}
+
+func TestValidateConfigLogFailedRequests(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.LogFailedRequests = true
+	assert.NoError(t, cfg.Validate())
+
+	cfg.FailedRequestSnippetSize = 0
+	assert.Equal(t, errInvalidSnippetSize, cfg.Validate())
+}
+
+func TestValidateConfigTokens(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Tokens = map[string]map[string]string{"token": {"tenant": "acme"}}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Tokens["other-token"] = map[string]string{"": "acme"}
+	assert.Equal(t, errEmptyTokenAttribute, cfg.Validate())
+}
+
+func TestValidateConfigMaxDecompressionRatio(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.MaxDecompressionRatio = 100
+	assert.NoError(t, cfg.Validate())
+
+	cfg.MaxDecompressionRatio = -1
+	assert.Equal(t, errNegativeDecompressionRatio, cfg.Validate())
+}
+
+func TestValidateConfigReusePort(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.ReusePort = true
+	if reusePortSupported {
+		assert.NoError(t, cfg.Validate())
+	} else {
+		assert.Equal(t, errReusePortUnsupported, cfg.Validate())
+	}
+}
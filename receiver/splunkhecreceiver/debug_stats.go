@@ -0,0 +1,70 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhecreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/splunkhecreceiver"
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// debugStatsPath is the path of the endpoint serving the debug stats when EnableDebugStats is set.
+const debugStatsPath = "/debug/hec-stats"
+
+// debugStats counts the requests and events handled by the receiver since it was created.
+type debugStats struct {
+	requests       atomic.Int64
+	events         atomic.Int64
+	decodeErrors   atomic.Int64
+	consumerErrors atomic.Int64
+}
+
+// debugStatsResponse is the body of the response of the debug stats endpoint.
+type debugStatsResponse struct {
+	Requests       int64 `json:"requests_total"`
+	Events         int64 `json:"events_total"`
+	DecodeErrors   int64 `json:"decode_errors_total"`
+	ConsumerErrors int64 `json:"consumer_errors_total"`
+}
+
+// recordConsumed counts numEvents events passed to the next consumer, which failed when err is not nil.
+func (s *debugStats) recordConsumed(numEvents int, err error) {
+	s.events.Add(int64(numEvents))
+	if err != nil {
+		s.consumerErrors.Add(1)
+	}
+}
+
+// handler answers GET requests with the current stats as a JSON object.
+func (s *debugStats) handler(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := jsoniter.Marshal(debugStatsResponse{
+		Requests:       s.requests.Load(),
+		Events:         s.events.Load(),
+		DecodeErrors:   s.decodeErrors.Load(),
+		ConsumerErrors: s.consumerErrors.Load(),
+	})
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	resp.Header().Set(httpContentTypeHeader, jsonContentType)
+	resp.WriteHeader(http.StatusOK)
+	_, _ = resp.Write(body)
+}
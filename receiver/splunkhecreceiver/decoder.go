@@ -0,0 +1,125 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhecreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/splunkhecreceiver"
+
+import (
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/splunk"
+)
+
+// decodedEvent is an event of a request body, along with the event as sent by the
+// client when it is captured.
+type decodedEvent struct {
+	msg splunk.Event
+	raw jsoniter.RawMessage
+	err error
+	// offset is the offset of the body after the event, only set when the body is buffered.
+	offset int
+}
+
+// eventDecoder returns the events of a request body in order, and false once
+// there are no more events.
+type eventDecoder func() (*decodedEvent, bool)
+
+// decodeSequentially returns a decoder decoding the events of dec as they are read. The
+// events are captured as sent by the client before being decoded when captureRaw is set.
+// position, when not nil, returns the offset of the body after the last event read.
+func decodeSequentially(dec *jsoniter.Decoder, captureRaw bool, position func() int) eventDecoder {
+	return func() (*decodedEvent, bool) {
+		if !dec.More() {
+			return nil, false
+		}
+		ev := &decodedEvent{}
+		if captureRaw {
+			if ev.err = dec.Decode(&ev.raw); ev.err == nil {
+				ev.err = jsoniter.Unmarshal(ev.raw, &ev.msg)
+			}
+		} else {
+			ev.err = dec.Decode(&ev.msg)
+		}
+		if ev.err != nil && position != nil {
+			ev.offset = position()
+		}
+		return ev, true
+	}
+}
+
+// decodeInParallel reads all the events of dec as sent by the client, then decodes
+// them with up to workers goroutines, each decoding a contiguous part of the events,
+// and returns a decoder returning them in the order of the body. Reading stops at the
+// first event that is not valid JSON, which is returned with its error. Events adding
+// up to less than minSize bytes are decoded by the calling goroutine, whatever the
+// Content-Length of the request, unknown for compressed bodies. It reports whether the
+// events were decoded concurrently.
+func decodeInParallel(dec *jsoniter.Decoder, workers int, minSize int64, position func() int) (eventDecoder, bool) {
+	var events []decodedEvent
+	var size int64
+	for dec.More() {
+		var ev decodedEvent
+		ev.err = dec.Decode(&ev.raw)
+		if position != nil {
+			ev.offset = position()
+		}
+		size += int64(len(ev.raw))
+		events = append(events, ev)
+		if ev.err != nil {
+			break
+		}
+	}
+
+	parallel := size >= minSize && len(events) > 1
+	if !parallel {
+		workers = 1
+	}
+	partSize := (len(events) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < len(events); start += partSize {
+		end := start + partSize
+		if end > len(events) {
+			end = len(events)
+		}
+		if !parallel {
+			unmarshalEvents(events[start:end])
+			continue
+		}
+		wg.Add(1)
+		go func(part []decodedEvent) {
+			defer wg.Done()
+			unmarshalEvents(part)
+		}(events[start:end])
+	}
+	wg.Wait()
+
+	next := 0
+	return func() (*decodedEvent, bool) {
+		if next == len(events) {
+			return nil, false
+		}
+		next++
+		return &events[next-1], true
+	}, parallel
+}
+
+// unmarshalEvents decodes the events captured as sent by the client.
+func unmarshalEvents(events []decodedEvent) {
+	for i := range events {
+		if events[i].err == nil {
+			events[i].err = jsoniter.Unmarshal(events[i].raw, &events[i].msg)
+		}
+	}
+}
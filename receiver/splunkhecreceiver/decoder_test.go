@@ -0,0 +1,81 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhecreceiver
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeAll(next eventDecoder) []*decodedEvent {
+	var events []*decodedEvent
+	for ev, ok := next(); ok; ev, ok = next() {
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestDecodeInParallel(t *testing.T) {
+	var body strings.Builder
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&body, `{"time":%d,"event":"line %d","fields":{"n":"%d"}}`, i, i, i)
+	}
+
+	for _, workers := range []int{1, 3, 8, 100} {
+		t.Run(fmt.Sprintf("workers_%d", workers), func(t *testing.T) {
+			sequential := decodeAll(decodeSequentially(jsoniter.NewDecoder(strings.NewReader(body.String())), true, nil))
+			next, concurrent := decodeInParallel(jsoniter.NewDecoder(strings.NewReader(body.String())), workers, 0, nil)
+			assert.True(t, concurrent)
+			parallel := decodeAll(next)
+			require.Len(t, parallel, 50)
+			assert.Equal(t, sequential, parallel)
+			for i, ev := range parallel {
+				assert.Equal(t, fmt.Sprintf("line %d", i), ev.msg.Event)
+			}
+		})
+	}
+
+	// Events adding up to less than the minimum size are decoded by the calling goroutine.
+	sequential := decodeAll(decodeSequentially(jsoniter.NewDecoder(strings.NewReader(body.String())), true, nil))
+	next, concurrent := decodeInParallel(jsoniter.NewDecoder(strings.NewReader(body.String())), 4, int64(body.Len()+1), nil)
+	assert.False(t, concurrent)
+	assert.Equal(t, sequential, decodeAll(next))
+}
+
+func TestDecodeInParallelErrors(t *testing.T) {
+	body := []byte(`{"event":"a"} {"event":"b","time":"not a number"} {"event":"c"} {"event":`)
+	reader := bytes.NewReader(body)
+	dec := jsoniter.NewDecoder(reader)
+	next, _ := decodeInParallel(dec, 2, 0, func() int {
+		return len(body) - reader.Len() - dec.Buffered().(*bytes.Reader).Len()
+	})
+	events := decodeAll(next)
+
+	// Reading stops at the event that is not valid JSON.
+	require.Len(t, events, 4)
+	assert.NoError(t, events[0].err)
+	assert.Equal(t, "a", events[0].msg.Event)
+	assert.Error(t, events[1].err, "the time is not a number")
+	assert.Equal(t, len(`{"event":"a"} {"event":"b","time":"not a number"}`), events[1].offset)
+	assert.NoError(t, events[2].err)
+	assert.Equal(t, "c", events[2].msg.Event)
+	assert.Error(t, events[3].err)
+}
@@ -0,0 +1,102 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhecreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/splunkhecreceiver"
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// minRatioCheckedSize is the decompressed size from which the decompression ratio of bodies
+// is checked. Smaller bodies of repetitive events compress well but cannot exhaust memory.
+const minRatioCheckedSize = 1 << 20
+
+var errDecompressionRatio = errors.New("gzip body exceeds the maximum decompression ratio")
+
+// byteCounter counts the bytes read from a reader.
+type byteCounter struct {
+	io.Reader
+	n int64
+}
+
+func (c *byteCounter) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type compressedBodyKey struct{}
+
+// countCompressedBody counts the bytes read from the bodies of requests with a content
+// encoding before confighttp decompresses them, so that the handlers can limit the
+// decompression ratio of the bodies they read.
+func countCompressedBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Body == nil || req.Header.Get("Content-Encoding") == "" {
+			next.ServeHTTP(resp, req)
+			return
+		}
+		compressed := &byteCounter{Reader: req.Body}
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{compressed, req.Body}
+		next.ServeHTTP(resp, req.WithContext(context.WithValue(req.Context(), compressedBodyKey{}, compressed)))
+	})
+}
+
+// compressedBody returns the counter of the compressed body of the request, or nil when the
+// body was not counted by countCompressedBody.
+func compressedBody(ctx context.Context) *byteCounter {
+	compressed, _ := ctx.Value(compressedBodyKey{}).(*byteCounter)
+	return compressed
+}
+
+// ratioLimitedReader reads a decompressed body, and fails with errDecompressionRatio once more
+// than maxRatio bytes were decompressed per byte read from the compressed body.
+type ratioLimitedReader struct {
+	io.ReadCloser
+	compressed   *byteCounter
+	maxRatio     int64
+	decompressed int64
+	tripped      bool
+}
+
+// newRatioLimitedReader returns a reader of the body decompressed by decompressor, which
+// reads the compressed body from compressed.
+func newRatioLimitedReader(decompressor io.ReadCloser, compressed *byteCounter, maxRatio int) *ratioLimitedReader {
+	return &ratioLimitedReader{ReadCloser: decompressor, compressed: compressed, maxRatio: int64(maxRatio)}
+}
+
+func (l *ratioLimitedReader) Read(p []byte) (int, error) {
+	if l.tripped {
+		return 0, errDecompressionRatio
+	}
+	n, err := l.ReadCloser.Read(p)
+	l.decompressed += int64(n)
+	if l.decompressed > minRatioCheckedSize && l.decompressed > l.maxRatio*l.compressed.n {
+		l.tripped = true
+		return n, errDecompressionRatio
+	}
+	return n, err
+}
+
+// exceeded reports whether the body exceeded the maximum decompression ratio. It is false
+// for a nil reader, used when the ratio is not limited.
+func (l *ratioLimitedReader) exceeded() bool {
+	return l != nil && l.tripped
+}
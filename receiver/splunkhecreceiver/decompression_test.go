@@ -0,0 +1,73 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhecreceiver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	_, err := gzipWriter.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, gzipWriter.Close())
+	return buf.Bytes()
+}
+
+func readRatioLimited(t *testing.T, compressedBody []byte, maxRatio int) (int64, *ratioLimitedReader, error) {
+	compressed := &byteCounter{Reader: bytes.NewReader(compressedBody)}
+	decompressor, err := gzip.NewReader(compressed)
+	require.NoError(t, err)
+	limited := newRatioLimitedReader(decompressor, compressed, maxRatio)
+	n, err := io.Copy(io.Discard, limited)
+	return n, limited, err
+}
+
+func TestRatioLimitedReader(t *testing.T) {
+	// 64MiB of zeros compress to about 128KiB, a ratio of about 500.
+	bomb := gzipBytes(t, make([]byte, 64<<20))
+	require.Less(t, len(bomb), 256<<10)
+
+	n, limited, err := readRatioLimited(t, bomb, 100)
+	assert.ErrorIs(t, err, errDecompressionRatio)
+	assert.True(t, limited.exceeded())
+	// Reading stops soon after the body passes the size from which the ratio is checked.
+	assert.Less(t, n, int64(2*minRatioCheckedSize))
+	_, err = limited.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, errDecompressionRatio)
+
+	n, limited, err = readRatioLimited(t, bomb, 2000)
+	assert.NoError(t, err)
+	assert.False(t, limited.exceeded())
+	assert.Equal(t, int64(64<<20), n)
+
+	// Small bodies are not checked, however well they compress.
+	n, limited, err = readRatioLimited(t, gzipBytes(t, make([]byte, minRatioCheckedSize)), 2)
+	assert.NoError(t, err)
+	assert.False(t, limited.exceeded())
+	assert.Equal(t, int64(minRatioCheckedSize), n)
+}
+
+func TestRatioLimitedReaderNil(t *testing.T) {
+	var limited *ratioLimitedReader
+	assert.False(t, limited.exceeded())
+}
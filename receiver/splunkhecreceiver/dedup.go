@@ -0,0 +1,127 @@
+// Copyright 2020, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhecreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/splunkhecreceiver"
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/splunk"
+)
+
+// eventDeduplicator drops events whose key was already seen within the TTL. The keys
+// are kept in an LRU cache, so the least recently seen keys are forgotten first once
+// the cache is full.
+type eventDeduplicator struct {
+	keyField string
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu sync.Mutex
+	// seen maps event keys to the time they were first seen.
+	seen *simplelru.LRU
+	// pending holds the keys of the events kept by filter, until they are recorded or released.
+	pending map[string]struct{}
+}
+
+func newEventDeduplicator(cfg DedupConfig) (*eventDeduplicator, error) {
+	seen, err := simplelru.NewLRU(cfg.CacheSize, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &eventDeduplicator{
+		keyField: cfg.KeyField,
+		ttl:      cfg.TTL,
+		now:      time.Now,
+		seen:     seen,
+		pending:  map[string]struct{}{},
+	}, nil
+}
+
+// filter returns the events that are not duplicates, along with their raw events when
+// rawEvents is set, and the keys of the events kept. The keys are reserved as pending,
+// so that concurrent requests drop the same events, and must be recorded with record
+// once the events are consumed, or released with release when they fail to be, so that
+// the events of a failed request are not dropped when the client retries it. Events
+// repeating the key of a previous event of the same request are duplicates.
+func (d *eventDeduplicator) filter(events []*splunk.Event, rawEvents [][]byte) ([]*splunk.Event, [][]byte, []string) {
+	kept := events[:0]
+	var keptRaw [][]byte
+	if rawEvents != nil {
+		keptRaw = rawEvents[:0]
+	}
+	var keys []string
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, event := range events {
+		key, ok := d.key(event)
+		if ok {
+			if d.isDuplicate(key, now) {
+				continue
+			}
+			d.pending[key] = struct{}{}
+			keys = append(keys, key)
+		}
+		kept = append(kept, event)
+		if rawEvents != nil {
+			keptRaw = append(keptRaw, rawEvents[i])
+		}
+	}
+	return kept, keptRaw, keys
+}
+
+// key returns the key of event, and false when the event has none. The key holds the type
+// of the value, so that values of different types printed the same are not duplicates.
+func (d *eventDeduplicator) key(event *splunk.Event) (string, bool) {
+	val, ok := selectField(event, d.keyField)
+	if !ok || val == nil {
+		return "", false
+	}
+	return fmt.Sprintf("%T:%v", val, val), true
+}
+
+// isDuplicate reports whether key is pending or was recorded within the TTL. It must be
+// called with mu held.
+func (d *eventDeduplicator) isDuplicate(key string, now time.Time) bool {
+	if _, ok := d.pending[key]; ok {
+		return true
+	}
+	firstSeen, ok := d.seen.Peek(key)
+	return ok && now.Sub(firstSeen.(time.Time)) < d.ttl
+}
+
+// record records the pending keys as seen now.
+func (d *eventDeduplicator) record(keys []string) {
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, key := range keys {
+		delete(d.pending, key)
+		d.seen.Add(key, now)
+	}
+}
+
+// release releases the pending keys of events that failed to be consumed.
+func (d *eventDeduplicator) release(keys []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, key := range keys {
+		delete(d.pending, key)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2020, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhecreceiver
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/splunk"
+)
+
+func eventWithID(id interface{}) *splunk.Event {
+	return &splunk.Event{Event: "foo", Fields: map[string]interface{}{"id": id}}
+}
+
+// seenBefore reports whether event is a duplicate, and records its key as consumed otherwise.
+func seenBefore(d *eventDeduplicator, event *splunk.Event) bool {
+	kept, _, keys := d.filter([]*splunk.Event{event}, nil)
+	d.record(keys)
+	return len(kept) == 0
+}
+
+func TestEventDeduplicator(t *testing.T) {
+	now := time.Unix(1000, 0)
+	d, err := newEventDeduplicator(DedupConfig{KeyField: "fields.id", CacheSize: 2, TTL: time.Minute})
+	require.NoError(t, err)
+	d.now = func() time.Time { return now }
+
+	assert.False(t, seenBefore(d, eventWithID("a")))
+	assert.True(t, seenBefore(d, eventWithID("a")))
+	assert.False(t, seenBefore(d, &splunk.Event{Event: "foo"}), "events without key are kept")
+	assert.False(t, seenBefore(d, &splunk.Event{Event: "foo"}), "events without key are kept")
+
+	now = now.Add(time.Minute)
+	assert.False(t, seenBefore(d, eventWithID("a")), "TTL elapsed")
+	assert.True(t, seenBefore(d, eventWithID("a")))
+
+	assert.False(t, seenBefore(d, eventWithID(1)))
+	assert.False(t, seenBefore(d, eventWithID(2)))
+	assert.False(t, seenBefore(d, eventWithID("a")), "least recently seen key evicted")
+}
+
+func TestEventDeduplicatorFilter(t *testing.T) {
+	d, err := newEventDeduplicator(DedupConfig{KeyField: "fields.id", CacheSize: 10, TTL: time.Minute})
+	require.NoError(t, err)
+
+	events := []*splunk.Event{eventWithID("a"), eventWithID("b"), eventWithID("a"), {Event: "foo"}}
+	rawEvents := [][]byte{[]byte("0"), []byte("1"), []byte("2"), []byte("3")}
+	events, rawEvents, keys := d.filter(events, rawEvents)
+	assert.Equal(t, []*splunk.Event{eventWithID("a"), eventWithID("b"), {Event: "foo"}}, events)
+	assert.Equal(t, [][]byte{[]byte("0"), []byte("1"), []byte("3")}, rawEvents)
+	assert.Equal(t, []string{"string:a", "string:b"}, keys)
+
+	// Keys are pending until the events are consumed, concurrent requests drop them.
+	events, _, _ = d.filter([]*splunk.Event{eventWithID("b"), eventWithID("c")}, nil)
+	assert.Equal(t, []*splunk.Event{eventWithID("c")}, events)
+
+	d.record(keys)
+	events, rawEvents, keys = d.filter([]*splunk.Event{eventWithID("b"), eventWithID("d")}, nil)
+	assert.Equal(t, []*splunk.Event{eventWithID("d")}, events)
+	assert.Nil(t, rawEvents)
+	assert.Equal(t, []string{"string:d"}, keys)
+}
+
+func TestEventDeduplicatorRelease(t *testing.T) {
+	d, err := newEventDeduplicator(DedupConfig{KeyField: "fields.id", CacheSize: 10, TTL: time.Minute})
+	require.NoError(t, err)
+
+	events, _, keys := d.filter([]*splunk.Event{eventWithID("a")}, nil)
+	require.Len(t, events, 1)
+	// The events failed to be consumed, they are kept when the request is retried.
+	d.release(keys)
+	events, _, keys = d.filter([]*splunk.Event{eventWithID("a")}, nil)
+	assert.Len(t, events, 1)
+	d.record(keys)
+	events, _, _ = d.filter([]*splunk.Event{eventWithID("a")}, nil)
+	assert.Empty(t, events)
+}
+
+func TestEventDeduplicatorKeyTypes(t *testing.T) {
+	d, err := newEventDeduplicator(DedupConfig{KeyField: "fields.id", CacheSize: 10, TTL: time.Minute})
+	require.NoError(t, err)
+
+	events, _, keys := d.filter([]*splunk.Event{eventWithID("1"), eventWithID(float64(1)), eventWithID(true), eventWithID("true")}, nil)
+	assert.Len(t, events, 4, "values of different types are not duplicates")
+	assert.Equal(t, []string{"string:1", "float64:1", "bool:true", "string:true"}, keys)
+}
+
+func TestEventDeduplicatorConcurrent(t *testing.T) {
+	d, err := newEventDeduplicator(DedupConfig{KeyField: "fields.id", CacheSize: 100, TTL: time.Minute})
+	require.NoError(t, err)
+
+	// The requests are all in flight when they are filtered, only one of them keeps each event.
+	var mu sync.Mutex
+	kept := map[string]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_, _, keys := d.filter([]*splunk.Event{eventWithID(fmt.Sprint(j))}, nil)
+				mu.Lock()
+				for _, key := range keys {
+					kept[key]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	require.Len(t, kept, 50)
+	for key, n := range kept {
+		assert.Equal(t, 1, n, key)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhecreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/splunkhecreceiver"
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// ndjsonContentType is the media type of responses holding the status of each event,
+	// requested with the Accept header or the eventStatusQueryParam query parameter.
+	ndjsonContentType     = "application/x-ndjson"
+	eventStatusQueryParam = "event_status"
+	httpAcceptHeader      = "Accept"
+
+	eventStatusOK    = "ok"
+	eventStatusError = "error"
+)
+
+// eventStatus is a line of the response holding the status of each event.
+type eventStatus struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+}
+
+// eventStatuses tracks whether the events of a request, in the order of the body,
+// were accepted by the receiver.
+type eventStatuses struct {
+	accepted []bool
+}
+
+// newEventStatuses returns the statuses of the events of req when the client asks
+// for the status of each event, and nil otherwise.
+func newEventStatuses(req *http.Request) *eventStatuses {
+	if eventStatus, err := strconv.ParseBool(req.URL.Query().Get(eventStatusQueryParam)); err == nil && eventStatus {
+		return &eventStatuses{}
+	}
+	for _, header := range req.Header.Values(httpAcceptHeader) {
+		for _, mediaRange := range strings.Split(header, ",") {
+			if mediaType, _, err := mime.ParseMediaType(mediaRange); err == nil && mediaType == ndjsonContentType {
+				return &eventStatuses{}
+			}
+		}
+	}
+	return nil
+}
+
+// accept records the next event as accepted. It does nothing on nil statuses.
+func (s *eventStatuses) accept() {
+	if s != nil {
+		s.accepted = append(s.accepted, true)
+	}
+}
+
+// reject records the next event as rejected. It does nothing on nil statuses.
+func (s *eventStatuses) reject() {
+	if s != nil {
+		s.accepted = append(s.accepted, false)
+	}
+}
+
+// write answers req with a JSON line holding the status of each event. The accepted
+// events are reported as failed when consumeErr, the error consuming them, is not nil.
+func (s *eventStatuses) write(resp http.ResponseWriter, req *http.Request, consumeErr error) error {
+	var body bytes.Buffer
+	enc := jsoniter.NewEncoder(&body)
+	for i, accepted := range s.accepted {
+		status := eventStatusOK
+		if !accepted || consumeErr != nil {
+			status = eventStatusError
+		}
+		if err := enc.Encode(eventStatus{Index: i, Status: status}); err != nil {
+			return err
+		}
+	}
+	resp.Header().Set(httpContentTypeHeader, ndjsonContentType)
+	return writeResponse(resp, req, http.StatusOK, body.Bytes())
+}
@@ -16,7 +16,9 @@ package splunkhecreceiver // import "github.com/open-telemetry/opentelemetry-col
 
 import (
 	"context"
+	"time"
 
+	"go.opencensus.io/stats/view"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config/confighttp"
 	"go.opentelemetry.io/collector/consumer"
@@ -36,10 +38,31 @@ const (
 
 	// Default endpoints to bind to.
 	defaultEndpoint = ":8088"
+
+	defaultDrainTimeout = 10 * time.Second
+
+	defaultRawEventAttribute = "splunk.raw"
+	defaultRecoveryWindow    = 30 * time.Second
+	defaultChannelAttribute  = "com.splunk.hec.channel"
+	defaultFlattenMaxDepth   = 5
+
+	defaultCoalesceMaxRecords = 8192
+	defaultCoalesceTimeout    = 200 * time.Millisecond
+
+	defaultDedupKeyField  = "fields.id"
+	defaultDedupCacheSize = 10000
+	defaultDedupTTL       = 5 * time.Minute
+
+	defaultFailedRequestSnippetSize = 1024
+
+	defaultDecodeWorkers          = 4
+	defaultDecodeMinContentLength = 1 << 20
 )
 
 // NewFactory creates a factory for Splunk HEC receiver.
 func NewFactory() receiver.Factory {
+	_ = view.Register(MetricViews()...)
+
 	return receiver.NewFactory(
 		typeStr,
 		createDefaultConfig,
@@ -60,8 +83,30 @@ func createDefaultConfig() component.Config {
 			Index:      splunk.DefaultIndexLabel,
 			Host:       conventions.AttributeHostName,
 		},
-		RawPath:    splunk.DefaultRawPath,
-		HealthPath: splunk.DefaultHealthPath,
+		RawPath:             splunk.DefaultRawPath,
+		HealthPath:          splunk.DefaultHealthPath,
+		BodyField:           eventField,
+		EventKey:            eventField,
+		DrainTimeout:        defaultDrainTimeout,
+		AllowedContentTypes: []string{jsonContentType},
+		RawEventAttribute:   defaultRawEventAttribute,
+		RecoveryWindow:      defaultRecoveryWindow,
+		ChannelAttribute:    defaultChannelAttribute,
+		FlattenMaxDepth:     defaultFlattenMaxDepth,
+		Coalesce: CoalesceConfig{
+			MaxRecords: defaultCoalesceMaxRecords,
+			Timeout:    defaultCoalesceTimeout,
+		},
+		Dedup: DedupConfig{
+			KeyField:  defaultDedupKeyField,
+			CacheSize: defaultDedupCacheSize,
+			TTL:       defaultDedupTTL,
+		},
+		FailedRequestSnippetSize: defaultFailedRequestSnippetSize,
+		ParallelDecode: ParallelDecodeConfig{
+			Workers:          defaultDecodeWorkers,
+			MinContentLength: defaultDecodeMinContentLength,
+		},
 	}
 }
 
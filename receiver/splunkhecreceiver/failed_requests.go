@@ -0,0 +1,99 @@
+// Copyright 2020, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhecreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/splunkhecreceiver"
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const redactedValue = `"REDACTED"`
+
+// failedRequestLogger logs a snippet of the bodies that could not be decoded, with
+// the values of the redacted fields replaced.
+type failedRequestLogger struct {
+	logger      *zap.Logger
+	snippetSize int
+	// redact matches the redacted fields with their value, which is the first submatch.
+	redact *regexp.Regexp
+}
+
+func newFailedRequestLogger(logger *zap.Logger, snippetSize int, redactedFields []string) *failedRequestLogger {
+	l := &failedRequestLogger{logger: logger, snippetSize: snippetSize}
+	if len(redactedFields) > 0 {
+		names := make([]string, len(redactedFields))
+		for i, field := range redactedFields {
+			names[i] = regexp.QuoteMeta(field)
+		}
+		l.redact = regexp.MustCompile(`"(?:` + strings.Join(names, "|") + `)"\s*:\s*("(?:[^"\\]|\\.)*"|[^\s,}\]]+)`)
+	}
+	return l
+}
+
+// bufferBody reads the whole body, so that a snippet of it can be logged if it
+// cannot be decoded. It returns the body and a reader of it.
+func bufferBody(body io.Reader) ([]byte, *bytes.Reader, error) {
+	buf, err := io.ReadAll(body)
+	return buf, bytes.NewReader(buf), err
+}
+
+// log logs the part of body around offset, the position at which decoding failed.
+func (l *failedRequestLogger) log(body []byte, offset int, err error) {
+	redacted, redactedOffset := l.redactBody(body, offset)
+	start := redactedOffset - l.snippetSize/2
+	if start < 0 {
+		start = 0
+	}
+	end := start + l.snippetSize
+	if end > len(redacted) {
+		end = len(redacted)
+		if start = end - l.snippetSize; start < 0 {
+			start = 0
+		}
+	}
+	l.logger.Warn("Failed to decode request body",
+		zap.Int("offset", offset),
+		zap.Int("body_size", len(body)),
+		zap.ByteString("snippet", redacted[start:end]),
+		zap.Error(err))
+}
+
+// redactBody replaces the values of the redacted fields of body, and returns the
+// redacted body with offset moved to the same position in it.
+func (l *failedRequestLogger) redactBody(body []byte, offset int) ([]byte, int) {
+	if l.redact == nil {
+		return body, offset
+	}
+	var redacted []byte
+	newOffset := offset
+	last := 0
+	for _, match := range l.redact.FindAllSubmatchIndex(body, -1) {
+		valueStart, valueEnd := match[2], match[3]
+		redacted = append(redacted, body[last:valueStart]...)
+		redacted = append(redacted, redactedValue...)
+		last = valueEnd
+		if valueEnd <= offset {
+			newOffset += len(redactedValue) - (valueEnd - valueStart)
+		} else if valueStart < offset {
+			newOffset = len(redacted)
+		}
+	}
+	redacted = append(redacted, body[last:]...)
+	return redacted, newOffset
+}
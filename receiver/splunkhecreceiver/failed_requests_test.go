@@ -0,0 +1,58 @@
+// Copyright 2020, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhecreceiver
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFailedRequestLoggerRedactBody(t *testing.T) {
+	l := newFailedRequestLogger(zap.NewNop(), 1024, []string{"password", "api.key"})
+	body := []byte(`{"event":"a","fields":{"password":"se\"cret","api.key": 1234,"user":"bob"}} {"event":`)
+	offset := len(body) - 1
+
+	redacted, newOffset := l.redactBody(body, offset)
+	assert.Equal(t, `{"event":"a","fields":{"password":"REDACTED","api.key": "REDACTED","user":"bob"}} {"event":`, string(redacted))
+	assert.Equal(t, len(redacted)-1, newOffset)
+
+	unchanged, sameOffset := newFailedRequestLogger(zap.NewNop(), 1024, nil).redactBody(body, offset)
+	assert.Equal(t, body, unchanged)
+	assert.Equal(t, offset, sameOffset)
+}
+
+func TestFailedRequestLoggerSnippet(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	l := newFailedRequestLogger(zap.New(core), 10, nil)
+	body := []byte("0123456789abcdefghij")
+
+	l.log(body, 10, errors.New("decode failed"))
+	l.log(body, 2, errors.New("decode failed"))
+	l.log(body, 19, errors.New("decode failed"))
+
+	require.Equal(t, 3, logs.Len())
+	var snippets []string
+	for _, entry := range logs.All() {
+		snippets = append(snippets, entry.ContextMap()["snippet"].(string))
+		assert.Equal(t, int64(20), entry.ContextMap()["body_size"])
+	}
+	assert.Equal(t, []string{"56789abcde", "0123456789", "abcdefghij"}, snippets)
+}
@@ -0,0 +1,59 @@
+// Copyright 2020, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhecreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/splunkhecreceiver"
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// downstreamHealth tracks consecutive failures of the next consumer. Once they reach
+// the failure threshold, the receiver is reported unhealthy for the recovery window,
+// after which requests are accepted again to probe whether the consumer recovered.
+type downstreamHealth struct {
+	failureThreshold    int64
+	recoveryWindow      time.Duration
+	consecutiveFailures atomic.Int64
+	// unhealthyUntil is the Unix time in nanoseconds until which the receiver is unhealthy.
+	unhealthyUntil atomic.Int64
+	now            func() time.Time
+}
+
+func newDownstreamHealth(failureThreshold int, recoveryWindow time.Duration) *downstreamHealth {
+	return &downstreamHealth{
+		failureThreshold: int64(failureThreshold),
+		recoveryWindow:   recoveryWindow,
+		now:              time.Now,
+	}
+}
+
+// record updates the health with the result of a call to the next consumer.
+func (h *downstreamHealth) record(err error) {
+	if h.failureThreshold <= 0 {
+		return
+	}
+	if err == nil {
+		h.consecutiveFailures.Store(0)
+		return
+	}
+	if h.consecutiveFailures.Add(1) >= h.failureThreshold {
+		h.unhealthyUntil.Store(h.now().Add(h.recoveryWindow).UnixNano())
+	}
+}
+
+// healthy reports whether requests should be passed to the next consumer.
+func (h *downstreamHealth) healthy() bool {
+	return h.failureThreshold <= 0 || h.now().UnixNano() >= h.unhealthyUntil.Load()
+}
@@ -0,0 +1,57 @@
+// Copyright 2020, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhecreceiver
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownstreamHealth(t *testing.T) {
+	now := time.Unix(1000, 0)
+	h := newDownstreamHealth(2, time.Minute)
+	h.now = func() time.Time { return now }
+	errConsumer := errors.New("consumer failed")
+
+	h.record(errConsumer)
+	assert.True(t, h.healthy(), "below the failure threshold")
+	h.record(nil)
+	h.record(errConsumer)
+	assert.True(t, h.healthy(), "a success resets the consecutive failures")
+
+	h.record(errConsumer)
+	assert.False(t, h.healthy(), "failure threshold reached")
+
+	now = now.Add(time.Minute)
+	assert.True(t, h.healthy(), "recovery window elapsed")
+	h.record(errConsumer)
+	assert.False(t, h.healthy(), "probe request failed")
+
+	now = now.Add(time.Minute)
+	h.record(nil)
+	h.record(errConsumer)
+	assert.True(t, h.healthy(), "consumer recovered")
+}
+
+func TestDownstreamHealthDisabled(t *testing.T) {
+	h := newDownstreamHealth(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		h.record(errors.New("consumer failed"))
+	}
+	assert.True(t, h.healthy())
+}
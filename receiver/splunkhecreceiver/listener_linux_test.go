@@ -0,0 +1,56 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package splunkhecreceiver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/common/testutil"
+)
+
+func Test_splunkhecReceiver_reusePort(t *testing.T) {
+	addr := testutil.GetAvailableLocalAddress(t)
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = addr
+	cfg.ReusePort = true
+
+	first, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, first.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		assert.NoError(t, first.Shutdown(context.Background()))
+	}()
+
+	second, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, second.Start(context.Background(), componenttest.NewNopHost()))
+	assert.NoError(t, second.Shutdown(context.Background()))
+
+	// The port cannot be shared by a receiver without SO_REUSEPORT.
+	cfg.ReusePort = false
+	third, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	assert.Error(t, third.Start(context.Background(), componenttest.NewNopHost()))
+}
@@ -0,0 +1,129 @@
+// Copyright 2020, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhecreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/splunkhecreceiver"
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+var (
+	tagInstanceName, _ = tag.NewKey("name")
+	tagStatusClass, _  = tag.NewKey("status_class")
+
+	statDroppedMetricEvents = stats.Int64("splunkhec_receiver_dropped_metric_events", "Number of metric events dropped by a logs receiver", stats.UnitDimensionless)
+	statDroppedEmptyEvents  = stats.Int64("splunkhec_receiver_dropped_empty_events", "Number of log events dropped because their body is empty", stats.UnitDimensionless)
+	statRequestLatency      = stats.Float64("splunkhec_receiver_request_latency", "Time taken to handle a request", stats.UnitMilliseconds)
+	statPayloadSize         = stats.Int64("splunkhec_receiver_payload_size", "Number of decompressed bytes read from the body of a request", stats.UnitBytes)
+)
+
+// MetricViews return metric views for the Splunk HEC receiver.
+func MetricViews() []*view.View {
+	tagKeys := []tag.Key{tagInstanceName}
+
+	countDroppedMetricEvents := &view.View{
+		Name:        statDroppedMetricEvents.Name(),
+		Measure:     statDroppedMetricEvents,
+		Description: statDroppedMetricEvents.Description(),
+		TagKeys:     tagKeys,
+		Aggregation: view.Sum(),
+	}
+
+	countDroppedEmptyEvents := &view.View{
+		Name:        statDroppedEmptyEvents.Name(),
+		Measure:     statDroppedEmptyEvents,
+		Description: statDroppedEmptyEvents.Description(),
+		TagKeys:     tagKeys,
+		Aggregation: view.Sum(),
+	}
+
+	requestTagKeys := []tag.Key{tagInstanceName, tagStatusClass}
+
+	distributionRequestLatency := &view.View{
+		Name:        statRequestLatency.Name(),
+		Measure:     statRequestLatency,
+		Description: statRequestLatency.Description(),
+		TagKeys:     requestTagKeys,
+		Aggregation: view.Distribution(1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000),
+	}
+
+	distributionPayloadSize := &view.View{
+		Name:        statPayloadSize.Name(),
+		Measure:     statPayloadSize,
+		Description: statPayloadSize.Description(),
+		TagKeys:     requestTagKeys,
+		Aggregation: view.Distribution(256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304, 16777216),
+	}
+
+	return []*view.View{
+		countDroppedMetricEvents,
+		countDroppedEmptyEvents,
+		distributionRequestLatency,
+		distributionPayloadSize,
+	}
+}
+
+// requestStats records the status code written to the response of a request, and
+// the number of bytes read from its decompressed body.
+type requestStats struct {
+	http.ResponseWriter
+	start       time.Time
+	status      int
+	payloadSize int64
+}
+
+func newRequestStats(resp http.ResponseWriter) *requestStats {
+	return &requestStats{ResponseWriter: resp, start: time.Now(), status: http.StatusOK}
+}
+
+func (s *requestStats) WriteHeader(statusCode int) {
+	s.status = statusCode
+	s.ResponseWriter.WriteHeader(statusCode)
+}
+
+// countBody returns a reader counting the bytes read from body.
+func (s *requestStats) countBody(body io.ReadCloser) io.ReadCloser {
+	return &countingReader{ReadCloser: body, count: &s.payloadSize}
+}
+
+type countingReader struct {
+	io.ReadCloser
+	count *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	*c.count += int64(n)
+	return n, err
+}
+
+// recordRequestStats records the latency and the payload size of a request,
+// labeled by the class of its response status, e.g. 2xx.
+func (r *splunkReceiver) recordRequestStats(ctx context.Context, s *requestStats) {
+	_ = stats.RecordWithTags(ctx, []tag.Mutator{
+		tag.Upsert(tagInstanceName, r.settings.ID.String()),
+		tag.Upsert(tagStatusClass, fmt.Sprintf("%dxx", s.status/100)),
+	},
+		statRequestLatency.M(float64(time.Since(s.start))/float64(time.Millisecond)),
+		statPayloadSize.M(s.payloadSize),
+	)
+}
@@ -16,26 +16,35 @@ package splunkhecreceiver // import "github.com/open-telemetry/opentelemetry-col
 
 import (
 	"bufio"
+	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	jsoniter "github.com/json-iterator/go"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/obsreport"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/receiver"
+	"go.opentelemetry.io/otel/propagation"
 	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/splunk"
 )
@@ -43,7 +52,7 @@ import (
 const (
 	defaultServerTimeout = 20 * time.Second
 
-	responseOK                        = "OK"
+	responseSuccess                   = "Success"
 	responseInvalidMethod             = `Only "POST" method is supported`
 	responseInvalidEncoding           = `"Content-Encoding" must be "gzip" or empty`
 	responseErrGzipReader             = "Error on gzip body"
@@ -51,10 +60,32 @@ const (
 	responseErrInternalServerError    = "Internal Server Error"
 	responseErrUnsupportedMetricEvent = "Unsupported metric event"
 	responseErrUnsupportedLogEvent    = "Unsupported log event"
-	responseErrHandlingIndexedFields  = `{"text":"Error in handling indexed fields","code":15,"invalid-event-number":%d}`
+	responseInvalidTimeQueryParam     = `"time" query parameter must be a number of seconds since the epoch`
+	responseErrServerBusy             = "Server is busy"
+	responseErrHandlingIndexedFields  = "Error in handling indexed fields"
+	// Splunk HEC response codes, see https://docs.splunk.com/Documentation/Splunk/9.0.1/Data/TroubleshootHTTPEventCollector#Possible_error_codes
+	responseCodeSuccess              = 0
+	responseCodeInvalidDataFormat    = 6
+	responseCodeInternalServerError  = 8
+	responseCodeServerBusy           = 9
+	responseCodeErrorInIndexedFields = 15
 	// Centralizing some HTTP and related string constants.
 	gzipEncoding              = "gzip"
+	jsonContentType           = "application/json"
 	httpContentEncodingHeader = "Content-Encoding"
+	httpAcceptEncodingHeader  = "Accept-Encoding"
+	httpVaryHeader            = "Vary"
+	httpContentTypeHeader     = "Content-Type"
+	httpChannelHeader         = "X-Splunk-Request-Channel"
+	channelQueryParam         = "channel"
+	timeQueryParam            = "time"
+	hostQueryParam            = "host"
+	sourceQueryParam          = "source"
+	sourcetypeQueryParam      = "sourcetype"
+	indexQueryParam           = "index"
+	// minGzipResponseSize is the size from which response bodies are compressed
+	// for clients accepting gzip, smaller bodies are not worth the overhead.
+	minGzipResponseSize = 1024
 )
 
 var (
@@ -63,17 +94,16 @@ var (
 	errEmptyEndpoint          = errors.New("empty endpoint")
 	errInvalidMethod          = errors.New("invalid http method")
 	errInvalidEncoding        = errors.New("invalid encoding")
-
-	okRespBody                = initJSONResponse(responseOK)
-	invalidMethodRespBody     = initJSONResponse(responseInvalidMethod)
-	invalidEncodingRespBody   = initJSONResponse(responseInvalidEncoding)
-	errGzipReaderRespBody     = initJSONResponse(responseErrGzipReader)
-	errUnmarshalBodyRespBody  = initJSONResponse(responseErrUnmarshalBody)
-	errInternalServerError    = initJSONResponse(responseErrInternalServerError)
-	errUnsupportedMetricEvent = initJSONResponse(responseErrUnsupportedMetricEvent)
-	errUnsupportedLogEvent    = initJSONResponse(responseErrUnsupportedLogEvent)
+	errInvalidTimeQueryParam  = errors.New("invalid time query parameter")
+	errUnhealthyConsumer      = errors.New("next consumer is unhealthy")
 )
 
+// hecResponse is the body of a Splunk HEC response.
+type hecResponse struct {
+	Text string `json:"text"`
+	Code int    `json:"code"`
+}
+
 // splunkReceiver implements the receiver.Metrics for Splunk HEC metric protocol.
 type splunkReceiver struct {
 	settings        receiver.CreateSettings
@@ -84,6 +114,12 @@ type splunkReceiver struct {
 	shutdownWG      sync.WaitGroup
 	obsrecv         *obsreport.Receiver
 	gzipReaderPool  *sync.Pool
+	health          *downstreamHealth
+	responses       hecResponses
+	coalescer       *logsCoalescer
+	dedup           *eventDeduplicator
+	failedRequests  *failedRequestLogger
+	debugStats      debugStats
 }
 
 var _ receiver.Metrics = (*splunkReceiver)(nil)
@@ -128,6 +164,11 @@ func newMetricsReceiver(
 		},
 		obsrecv:        obsrecv,
 		gzipReaderPool: &sync.Pool{New: func() interface{} { return new(gzip.Reader) }},
+		health:         newDownstreamHealth(config.FailureThreshold, config.RecoveryWindow),
+		responses:      newHECResponses(config.Responses),
+	}
+	if config.LogFailedRequests {
+		r.failedRequests = newFailedRequestLogger(settings.Logger, config.FailedRequestSnippetSize, config.RedactedFields)
 	}
 
 	return r, nil
@@ -173,6 +214,19 @@ func newLogsReceiver(
 		},
 		gzipReaderPool: &sync.Pool{New: func() interface{} { return new(gzip.Reader) }},
 		obsrecv:        obsrecv,
+		health:         newDownstreamHealth(config.FailureThreshold, config.RecoveryWindow),
+		responses:      newHECResponses(config.Responses),
+	}
+	if config.Coalesce.Enabled {
+		r.coalescer = newLogsCoalescer(nextConsumer, settings.Logger, r.health, config.Coalesce)
+	}
+	if config.LogFailedRequests {
+		r.failedRequests = newFailedRequestLogger(settings.Logger, config.FailedRequestSnippetSize, config.RedactedFields)
+	}
+	if config.Dedup.Enabled {
+		if r.dedup, err = newEventDeduplicator(config.Dedup); err != nil {
+			return nil, err
+		}
 	}
 
 	return r, nil
@@ -181,7 +235,7 @@ func newLogsReceiver(
 // Start tells the receiver to start its processing.
 // By convention the consumer of the received data is set when the receiver
 // instance is created.
-func (r *splunkReceiver) Start(_ context.Context, host component.Host) error {
+func (r *splunkReceiver) Start(ctx context.Context, host component.Host) error {
 	// server.Handler will be nil on initial call, otherwise noop.
 	if r.server != nil && r.server.Handler != nil {
 		return nil
@@ -189,13 +243,16 @@ func (r *splunkReceiver) Start(_ context.Context, host component.Host) error {
 
 	var ln net.Listener
 	// set up the listener
-	ln, err := r.config.HTTPServerSettings.ToListener()
+	ln, err := r.listen(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to bind to address %s: %w", r.config.Endpoint, err)
 	}
 
 	mx := mux.NewRouter()
 	mx.NewRoute().Path(r.config.HealthPath).HandlerFunc(r.handleHealthReq)
+	if r.config.EnableDebugStats {
+		mx.NewRoute().Path(debugStatsPath).HandlerFunc(r.debugStats.handler)
+	}
 	if r.logsConsumer != nil {
 		mx.NewRoute().Path(r.config.RawPath).HandlerFunc(r.handleRawReq)
 	}
@@ -205,6 +262,15 @@ func (r *splunkReceiver) Start(_ context.Context, host component.Host) error {
 	if err != nil {
 		return err
 	}
+	if r.config.MaxDecompressionRatio > 0 {
+		// confighttp decompresses bodies innermost, count them on the way in.
+		r.server.Handler = countCompressedBody(r.server.Handler)
+	}
+	r.server.Handler = extractTraceContext(r.server.Handler)
+	if r.config.EnableH2C {
+		// Serve cleartext HTTP/2 as well, HTTP/2 over TLS is always enabled.
+		r.server.Handler = h2c.NewHandler(r.server.Handler, &http2.Server{})
+	}
 
 	// TODO: Evaluate what properties should be configurable, for now
 	//		set some hard-coded values.
@@ -222,41 +288,107 @@ func (r *splunkReceiver) Start(_ context.Context, host component.Host) error {
 	return err
 }
 
+// listen binds the endpoint of the receiver, with SO_REUSEPORT when ReusePort is enabled.
+func (r *splunkReceiver) listen(ctx context.Context) (net.Listener, error) {
+	if !r.config.ReusePort {
+		return r.config.HTTPServerSettings.ToListener()
+	}
+	lc := net.ListenConfig{Control: reusePortControl}
+	ln, err := lc.Listen(ctx, "tcp", r.config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	// Serve TLS as HTTPServerSettings.ToListener does.
+	if r.config.TLSSetting != nil {
+		tlsCfg, err := r.config.TLSSetting.LoadTLSConfig()
+		if err != nil {
+			_ = ln.Close()
+			return nil, err
+		}
+		tlsCfg.NextProtos = []string{http2.NextProtoTLS, "http/1.1"}
+		ln = tls.NewListener(ln, tlsCfg)
+	}
+	return ln, nil
+}
+
+// extractTraceContext sets the W3C trace context of the request headers, if any, as the
+// parent of the spans created while handling the request, regardless of the propagators
+// configured for the collector telemetry. Without trace context headers, the spans
+// start a new trace.
+func extractTraceContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		ctx := propagation.TraceContext{}.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		next.ServeHTTP(resp, req.WithContext(ctx))
+	})
+}
+
 // Shutdown tells the receiver that should stop reception,
 // giving it a chance to perform any necessary clean-up.
-func (r *splunkReceiver) Shutdown(context.Context) error {
-	err := r.server.Close()
+// In-flight requests are given up to the configured drain timeout to
+// complete before their connections are closed.
+func (r *splunkReceiver) Shutdown(ctx context.Context) error {
+	if r.config.DrainTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.config.DrainTimeout)
+		defer cancel()
+	}
+	err := r.server.Shutdown(ctx)
+	if err != nil {
+		r.settings.Logger.Warn("In-flight requests did not complete before shutdown, closing connections", zap.Error(err))
+		err = r.server.Close()
+	}
 	r.shutdownWG.Wait()
+	if r.coalescer != nil {
+		r.coalescer.shutdown()
+	}
 	return err
 }
 
 func (r *splunkReceiver) handleRawReq(resp http.ResponseWriter, req *http.Request) {
+	r.debugStats.requests.Add(1)
+	reqStats := newRequestStats(resp)
+	resp = reqStats
+	defer r.recordRequestStats(req.Context(), reqStats)
 	ctx := req.Context()
 	ctx = r.obsrecv.StartLogsOp(ctx)
 
 	if req.Method != http.MethodPost {
-		r.failRequest(ctx, resp, http.StatusBadRequest, invalidMethodRespBody, 0, errInvalidMethod)
+		r.failRequest(ctx, resp, req, http.StatusBadRequest, r.responses.invalidMethod, 0, errInvalidMethod)
+		return
+	}
+
+	if !r.health.healthy() {
+		r.failRequest(ctx, resp, req, http.StatusServiceUnavailable, r.responses.serverBusy, 0, errUnhealthyConsumer)
 		return
 	}
 
 	encoding := req.Header.Get(httpContentEncodingHeader)
 	if encoding != "" && encoding != gzipEncoding {
-		r.failRequest(ctx, resp, http.StatusUnsupportedMediaType, invalidEncodingRespBody, 0, errInvalidEncoding)
+		r.failRequest(ctx, resp, req, http.StatusUnsupportedMediaType, r.responses.invalidEncoding, 0, errInvalidEncoding)
+		return
+	}
+
+	ts, err := rawTimestamp(req)
+	if err != nil {
+		r.failRequest(ctx, resp, req, http.StatusBadRequest, r.responses.invalidTimeQueryParam, 0, err)
 		return
 	}
 
-	if req.ContentLength == 0 {
+	if !hasBody(req) {
 		r.obsrecv.EndLogsOp(ctx, typeStr, 0, nil)
 		return
 	}
 
 	bodyReader := req.Body
+	// Bodies decompressed by confighttp are counted before reaching the handler.
+	compressed := compressedBody(req.Context())
 	if encoding == gzipEncoding {
 		reader := r.gzipReaderPool.Get().(*gzip.Reader)
-		err := reader.Reset(bodyReader)
+		compressed = &byteCounter{Reader: bodyReader}
+		err := reader.Reset(compressed)
 
 		if err != nil {
-			r.failRequest(ctx, resp, http.StatusBadRequest, errGzipReaderRespBody, 0, err)
+			r.failRequest(ctx, resp, req, http.StatusBadRequest, r.responses.gzipReader, 0, err)
 			_, _ = io.ReadAll(req.Body)
 			_ = req.Body.Close()
 			return
@@ -264,11 +396,36 @@ func (r *splunkReceiver) handleRawReq(resp http.ResponseWriter, req *http.Reques
 		bodyReader = reader
 		defer r.gzipReaderPool.Put(reader)
 	}
+	if compressed != nil && r.config.MaxDecompressionRatio > 0 {
+		bodyReader = newRatioLimitedReader(bodyReader, compressed, r.config.MaxDecompressionRatio)
+	}
+	bodyReader = reqStats.countBody(bodyReader)
+
+	numRecords, consumerErr := r.consumeRawLines(ctx, bodyReader, req, ts)
+
+	_ = bodyReader.Close()
 
+	if errors.Is(consumerErr, errDecompressionRatio) {
+		r.failRequest(ctx, resp, req, http.StatusBadRequest, r.responses.gzipReader, 0, consumerErr)
+	} else if consumerErr != nil {
+		r.failRequest(ctx, resp, req, http.StatusInternalServerError, r.responses.internalServerError, numRecords, consumerErr)
+	} else {
+		resp.WriteHeader(http.StatusOK)
+		r.obsrecv.EndLogsOp(ctx, typeStr, numRecords, nil)
+	}
+}
+
+// consumeRawLines sends every line of the body as a log record to the logs consumer,
+// and returns the number of records sent. Records are timestamped with ts unless the
+// line sets its own timestamp, and all share the metadata of the request. It fails with
+// errDecompressionRatio, without sending any record, when the body inflates beyond the limit.
+func (r *splunkReceiver) consumeRawLines(ctx context.Context, bodyReader io.Reader, req *http.Request, ts pcommon.Timestamp) (int, error) {
 	sc := bufio.NewScanner(bodyReader)
 
 	ld := plog.NewLogs()
 	rl := ld.ResourceLogs().AppendEmpty()
+	metadata := r.rawMetadata(req)
+	putResourceMetadata(rl.Resource(), metadata, r.config)
 	resourceCustomizer := r.createResourceCustomizer(req)
 	if resourceCustomizer != nil {
 		resourceCustomizer(rl.Resource())
@@ -277,22 +434,39 @@ func (r *splunkReceiver) handleRawReq(resp http.ResponseWriter, req *http.Reques
 
 	for sc.Scan() {
 		logRecord := sl.LogRecords().AppendEmpty()
-		logLine := sc.Text()
-		logRecord.Body().SetStr(logLine)
+		rawLineToLogRecord(sc.Text(), ts, logRecord)
+		putLogRecordMetadata(logRecord, metadata, r.config)
 	}
-	consumerErr := r.logsConsumer.ConsumeLogs(ctx, ld)
-
-	_ = bodyReader.Close()
+	if err := sc.Err(); errors.Is(err, errDecompressionRatio) {
+		// None of the lines of a body inflating beyond the limit are sent.
+		return 0, err
+	}
+	numRecords := sl.LogRecords().Len()
+	err := r.sendLogs(ctx, ld)
+	r.debugStats.recordConsumed(numRecords, err)
+	return numRecords, err
+}
 
-	if consumerErr != nil {
-		r.failRequest(ctx, resp, http.StatusInternalServerError, errInternalServerError, sl.LogRecords().Len(), consumerErr)
-	} else {
-		resp.WriteHeader(http.StatusOK)
-		r.obsrecv.EndLogsOp(ctx, typeStr, sl.LogRecords().Len(), nil)
+// isRawContentType reports whether the request has a text media type listed in
+// AllowedContentTypes, in which case its body holds raw log lines instead of JSON events.
+func (r *splunkReceiver) isRawContentType(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get(httpContentTypeHeader))
+	if err != nil || !strings.HasPrefix(mediaType, "text/") {
+		return false
 	}
+	for _, contentType := range r.config.AllowedContentTypes {
+		if allowed, _, _ := mime.ParseMediaType(contentType); allowed == mediaType {
+			return true
+		}
+	}
+	return false
 }
 
 func (r *splunkReceiver) handleReq(resp http.ResponseWriter, req *http.Request) {
+	r.debugStats.requests.Add(1)
+	reqStats := newRequestStats(resp)
+	resp = reqStats
+	defer r.recordRequestStats(req.Context(), reqStats)
 	ctx := req.Context()
 	if r.logsConsumer == nil {
 		ctx = r.obsrecv.StartMetricsOp(ctx)
@@ -301,147 +475,433 @@ func (r *splunkReceiver) handleReq(resp http.ResponseWriter, req *http.Request)
 	}
 
 	if req.Method != http.MethodPost {
-		r.failRequest(ctx, resp, http.StatusBadRequest, invalidMethodRespBody, 0, errInvalidMethod)
+		r.failRequest(ctx, resp, req, http.StatusBadRequest, r.responses.invalidMethod, 0, errInvalidMethod)
+		return
+	}
+
+	if !r.health.healthy() {
+		r.failRequest(ctx, resp, req, http.StatusServiceUnavailable, r.responses.serverBusy, 0, errUnhealthyConsumer)
 		return
 	}
 
 	encoding := req.Header.Get(httpContentEncodingHeader)
 	if encoding != "" && encoding != gzipEncoding {
-		r.failRequest(ctx, resp, http.StatusUnsupportedMediaType, invalidEncodingRespBody, 0, errInvalidEncoding)
+		r.failRequest(ctx, resp, req, http.StatusUnsupportedMediaType, r.responses.invalidEncoding, 0, errInvalidEncoding)
+		return
+	}
+
+	if !hasBody(req) {
+		r.acceptNoEvents(ctx, resp, req, newEventStatuses(req))
 		return
 	}
 
 	bodyReader := req.Body
+	// Bodies decompressed by confighttp are counted before reaching the handler.
+	compressed := compressedBody(req.Context())
 	if encoding == gzipEncoding {
 		reader := r.gzipReaderPool.Get().(*gzip.Reader)
-		err := reader.Reset(bodyReader)
+		compressed = &byteCounter{Reader: bodyReader}
+		err := reader.Reset(compressed)
 		if err != nil {
-			r.failRequest(ctx, resp, http.StatusBadRequest, errGzipReaderRespBody, 0, err)
+			r.failRequest(ctx, resp, req, http.StatusBadRequest, r.responses.gzipReader, 0, err)
 			return
 		}
 		bodyReader = reader
 		defer r.gzipReaderPool.Put(reader)
 	}
+	var limited *ratioLimitedReader
+	if compressed != nil && r.config.MaxDecompressionRatio > 0 {
+		limited = newRatioLimitedReader(bodyReader, compressed, r.config.MaxDecompressionRatio)
+		bodyReader = limited
+	}
+	bodyReader = reqStats.countBody(bodyReader)
 
-	if req.ContentLength == 0 {
-		if _, err := resp.Write(okRespBody); err != nil {
-			r.failRequest(ctx, resp, http.StatusInternalServerError, errInternalServerError, 0, err)
-		}
+	if r.isRawContentType(req) {
+		r.handleRawLines(ctx, resp, req, bodyReader)
 		return
 	}
 
+	var body []byte
+	var bufferedBody *bytes.Reader
+	if r.failedRequests != nil {
+		var err error
+		if body, bufferedBody, err = bufferBody(bodyReader); limited.exceeded() {
+			r.failRequest(ctx, resp, req, http.StatusBadRequest, r.responses.gzipReader, 0, errDecompressionRatio)
+			return
+		} else if err != nil {
+			r.failRequest(ctx, resp, req, http.StatusBadRequest, r.responses.unmarshalBody, 0, err)
+			return
+		}
+		bodyReader = io.NopCloser(bufferedBody)
+	}
+
 	dec := jsoniter.NewDecoder(bodyReader)
+	var position func() int
+	if r.failedRequests != nil {
+		position = func() int {
+			unread, _ := io.Copy(io.Discard, dec.Buffered())
+			return len(body) - bufferedBody.Len() - int(unread)
+		}
+	}
 
 	var events []*splunk.Event
+	var rawEvents [][]byte
+	var droppedMetricEvents, droppedEmptyEvents int64
+	statuses := newEventStatuses(req)
+	keepRawEvents := r.logsConsumer != nil && r.config.KeepRawEvent
+	customEventKey := r.logsConsumer != nil && r.config.EventKey != "" && r.config.EventKey != eventField
+	exactTimeNanos := r.logsConsumer != nil && r.config.TimeNanosField != ""
 
-	for dec.More() {
-		var msg splunk.Event
-		err := dec.Decode(&msg)
-		if err != nil {
-			r.failRequest(ctx, resp, http.StatusBadRequest, errUnmarshalBodyRespBody, len(events), err)
-			return
+	// Capture the events as sent by the client before decoding them when needed.
+	next := decodeSequentially(dec, keepRawEvents || customEventKey || exactTimeNanos, position)
+	if r.config.ParallelDecode.Enabled {
+		// The size of the events read decides, compressed bodies have no Content-Length.
+		var parallel bool
+		next, parallel = decodeInParallel(dec, r.config.ParallelDecode.Workers, r.config.ParallelDecode.MinContentLength, position)
+		if parallel {
+			r.settings.Logger.Debug("Decoded the events of the request concurrently", zap.Int("workers", r.config.ParallelDecode.Workers))
 		}
-
-		for _, v := range msg.Fields {
-			if !isFlatJSONField(v) {
-				r.failRequest(ctx, resp, http.StatusBadRequest, []byte(fmt.Sprintf(responseErrHandlingIndexedFields, len(events))), len(events), nil)
-				return
+	}
+	for ev, ok := next(); ok; ev, ok = next() {
+		msg, rawEvent, err := &ev.msg, ev.raw, ev.err
+		if limited.exceeded() {
+			break
+		}
+		if err != nil {
+			r.debugStats.decodeErrors.Add(1)
+			if r.failedRequests != nil {
+				r.failedRequests.log(body, ev.offset, err)
+			}
+			if statuses != nil {
+				// The events following an event that cannot be decoded are not read.
+				statuses.reject()
+				break
 			}
+			r.failRequest(ctx, resp, req, http.StatusBadRequest, r.responses.unmarshalBody, len(events), err)
+			return
 		}
-		if msg.IsMetric() {
-			if r.metricsConsumer == nil {
-				r.failRequest(ctx, resp, http.StatusBadRequest, errUnsupportedMetricEvent, len(events), err)
-				return
+		if respBody, err := r.checkEvent(msg, rawEvent, customEventKey, len(events)); respBody != nil {
+			if statuses != nil {
+				statuses.reject()
+				continue
 			}
-		} else if r.logsConsumer == nil {
-			r.failRequest(ctx, resp, http.StatusBadRequest, errUnsupportedLogEvent, len(events), err)
+			r.failRequest(ctx, resp, req, http.StatusBadRequest, respBody, len(events), err)
 			return
 		}
+		statuses.accept()
+		if exactTimeNanos && !msg.IsMetric() {
+			setExactInteger(msg, rawEvent, r.config.TimeNanosField, r.config.EventKey)
+		}
 
-		events = append(events, &msg)
+		if msg.IsMetric() && r.metricsConsumer == nil {
+			droppedMetricEvents++
+			continue
+		}
+		if !msg.IsMetric() && r.config.DropEmptyEvents && hasEmptyBody(msg, r.config) {
+			droppedEmptyEvents++
+			continue
+		}
+		events = append(events, msg)
+		if keepRawEvents {
+			rawEvents = append(rawEvents, rawEvent)
+		}
+	}
+	if limited.exceeded() {
+		// The body may have been cut short before any event failed to be decoded.
+		r.failRequest(ctx, resp, req, http.StatusBadRequest, r.responses.gzipReader, 0, errDecompressionRatio)
+		return
+	}
+	if droppedMetricEvents > 0 {
+		r.settings.Logger.Debug("Dropped metric events sent to a logs receiver", zap.Int64("count", droppedMetricEvents))
+		_ = stats.RecordWithTags(ctx, []tag.Mutator{tag.Upsert(tagInstanceName, r.settings.ID.String())}, statDroppedMetricEvents.M(droppedMetricEvents))
+	}
+	if droppedEmptyEvents > 0 {
+		r.settings.Logger.Debug("Dropped log events with an empty body", zap.Int64("count", droppedEmptyEvents))
+		_ = stats.RecordWithTags(ctx, []tag.Mutator{tag.Upsert(tagInstanceName, r.settings.ID.String())}, statDroppedEmptyEvents.M(droppedEmptyEvents))
+	}
+	if len(events) == 0 {
+		// All the events were dropped, or rejected, there is nothing to pass to the next consumer.
+		r.acceptNoEvents(ctx, resp, req, statuses)
+		return
 	}
 	if r.logsConsumer != nil {
-		r.consumeLogs(ctx, events, resp, req)
+		r.consumeLogs(ctx, events, rawEvents, statuses, resp, req)
 	} else {
-		r.consumeMetrics(ctx, events, resp, req)
+		r.consumeMetrics(ctx, events, statuses, resp, req)
+	}
+}
+
+// checkEvent returns the body of the response rejecting a request holding msg, along with
+// the error to report, when the event is invalid or is not supported by the receiver, and
+// nil otherwise. eventNumber is the number of valid events preceding the event.
+func (r *splunkReceiver) checkEvent(msg *splunk.Event, rawEvent []byte, customEventKey bool, eventNumber int) ([]byte, error) {
+	for _, v := range msg.Fields {
+		if !isFlatJSONField(v) {
+			return r.responses.indexedFields(eventNumber), nil
+		}
+	}
+	if customEventKey {
+		if err := applyEventKey(msg, rawEvent, r.config.EventKey); err != nil {
+			return r.responses.unmarshalBody, err
+		}
 	}
+	if msg.IsMetric() {
+		if r.metricsConsumer == nil && !r.config.DropMetricEvents {
+			return r.responses.unsupportedMetricEvent, nil
+		}
+	} else if r.logsConsumer == nil {
+		return r.responses.unsupportedLogEvent, nil
+	}
+	return nil, nil
 }
 
-func (r *splunkReceiver) consumeMetrics(ctx context.Context, events []*splunk.Event, resp http.ResponseWriter, req *http.Request) {
+// handleRawLines answers a request to the event endpoint whose body holds raw log lines.
+func (r *splunkReceiver) handleRawLines(ctx context.Context, resp http.ResponseWriter, req *http.Request, bodyReader io.Reader) {
+	if r.logsConsumer == nil {
+		r.failRequest(ctx, resp, req, http.StatusBadRequest, r.responses.unsupportedLogEvent, 0, nil)
+		return
+	}
+
+	ts, err := rawTimestamp(req)
+	if err != nil {
+		r.failRequest(ctx, resp, req, http.StatusBadRequest, r.responses.invalidTimeQueryParam, 0, err)
+		return
+	}
+
+	numRecords, consumerErr := r.consumeRawLines(ctx, bodyReader, req, ts)
+	if errors.Is(consumerErr, errDecompressionRatio) {
+		r.failRequest(ctx, resp, req, http.StatusBadRequest, r.responses.gzipReader, 0, consumerErr)
+		return
+	}
+	if consumerErr != nil {
+		r.failRequest(ctx, resp, req, http.StatusInternalServerError, r.responses.internalServerError, numRecords, consumerErr)
+		return
+	}
+	if err := writeResponse(resp, req, http.StatusOK, r.responses.success); err != nil {
+		r.failRequest(ctx, resp, req, http.StatusInternalServerError, r.responses.internalServerError, numRecords, err)
+		return
+	}
+	r.obsrecv.EndLogsOp(ctx, typeStr, numRecords, nil)
+}
+
+func (r *splunkReceiver) consumeMetrics(ctx context.Context, events []*splunk.Event, statuses *eventStatuses, resp http.ResponseWriter, req *http.Request) {
 	resourceCustomizer := r.createResourceCustomizer(req)
 	md, _ := splunkHecToMetricsData(r.settings.Logger, events, resourceCustomizer, r.config)
 
 	decodeErr := r.metricsConsumer.ConsumeMetrics(ctx, md)
+	r.health.record(decodeErr)
+	r.debugStats.recordConsumed(len(events), decodeErr)
 	r.obsrecv.EndMetricsOp(ctx, typeStr, len(events), decodeErr)
 
-	if decodeErr != nil {
-		r.failRequest(ctx, resp, http.StatusInternalServerError, errInternalServerError, len(events), decodeErr)
+	if statuses != nil {
+		r.writeEventStatuses(resp, req, statuses, decodeErr)
+	} else if decodeErr != nil {
+		r.failRequest(ctx, resp, req, http.StatusInternalServerError, r.responses.internalServerError, len(events), decodeErr)
 	} else {
-		resp.WriteHeader(http.StatusOK)
-		_, err := resp.Write(okRespBody)
-		if err != nil {
-			r.failRequest(ctx, resp, http.StatusInternalServerError, errInternalServerError, len(events), err)
+		if err := writeResponse(resp, req, http.StatusOK, r.responses.success); err != nil {
+			r.failRequest(ctx, resp, req, http.StatusInternalServerError, r.responses.internalServerError, len(events), err)
 		}
 	}
 }
 
-func (r *splunkReceiver) consumeLogs(ctx context.Context, events []*splunk.Event, resp http.ResponseWriter, req *http.Request) {
+func (r *splunkReceiver) consumeLogs(ctx context.Context, events []*splunk.Event, rawEvents [][]byte, statuses *eventStatuses, resp http.ResponseWriter, req *http.Request) {
+	var dedupKeys []string
+	if r.dedup != nil {
+		events, rawEvents, dedupKeys = r.dedup.filter(events, rawEvents)
+		if len(events) == 0 {
+			r.acceptNoEvents(ctx, resp, req, statuses)
+			return
+		}
+	}
 	resourceCustomizer := r.createResourceCustomizer(req)
-	ld, err := splunkHecToLogData(r.settings.Logger, events, resourceCustomizer, r.config)
+	ld, err := splunkHecToLogData(r.settings.Logger, events, rawEvents, resourceCustomizer, r.config)
 	if err != nil {
-		r.failRequest(ctx, resp, http.StatusBadRequest, errUnmarshalBodyRespBody, len(events), err)
+		if r.dedup != nil {
+			r.dedup.release(dedupKeys)
+		}
+		r.debugStats.decodeErrors.Add(1)
+		if statuses != nil {
+			r.endOp(ctx, len(events), err)
+			r.writeEventStatuses(resp, req, statuses, err)
+			return
+		}
+		r.failRequest(ctx, resp, req, http.StatusBadRequest, r.responses.unmarshalBody, len(events), err)
 		return
 	}
 
-	decodeErr := r.logsConsumer.ConsumeLogs(ctx, ld)
+	decodeErr := r.sendLogs(ctx, ld)
+	if r.dedup != nil {
+		// Keys are only recorded once their events are consumed, so the events of failed requests are kept on retry.
+		if decodeErr == nil {
+			r.dedup.record(dedupKeys)
+		} else {
+			r.dedup.release(dedupKeys)
+		}
+	}
+	r.debugStats.recordConsumed(len(events), decodeErr)
 	r.obsrecv.EndLogsOp(ctx, typeStr, len(events), decodeErr)
-	if decodeErr != nil {
-		r.failRequest(ctx, resp, http.StatusInternalServerError, errInternalServerError, len(events), decodeErr)
+	if statuses != nil {
+		r.writeEventStatuses(resp, req, statuses, decodeErr)
+	} else if decodeErr != nil {
+		r.failRequest(ctx, resp, req, http.StatusInternalServerError, r.responses.internalServerError, len(events), decodeErr)
 	} else {
-		resp.WriteHeader(http.StatusOK)
-		if _, err := resp.Write(okRespBody); err != nil {
-			r.failRequest(ctx, resp, http.StatusInternalServerError, errInternalServerError, len(events), err)
+		if err := writeResponse(resp, req, http.StatusOK, r.responses.success); err != nil {
+			r.failRequest(ctx, resp, req, http.StatusInternalServerError, r.responses.internalServerError, len(events), err)
 		}
 	}
 }
 
+// acceptNoEvents answers a request without any event to consume, with the status of each
+// of its events when statuses is not nil.
+func (r *splunkReceiver) acceptNoEvents(ctx context.Context, resp http.ResponseWriter, req *http.Request, statuses *eventStatuses) {
+	if statuses != nil {
+		r.writeEventStatuses(resp, req, statuses, nil)
+		r.endOp(ctx, 0, nil)
+		return
+	}
+	if err := writeResponse(resp, req, http.StatusOK, r.responses.success); err != nil {
+		r.failRequest(ctx, resp, req, http.StatusInternalServerError, r.responses.internalServerError, 0, err)
+		return
+	}
+	r.endOp(ctx, 0, nil)
+}
+
+// writeEventStatuses answers a request with the status of each of its events, which
+// failed to be consumed when consumeErr is not nil.
+func (r *splunkReceiver) writeEventStatuses(resp http.ResponseWriter, req *http.Request, statuses *eventStatuses, consumeErr error) {
+	if err := statuses.write(resp, req, consumeErr); err != nil {
+		r.settings.Logger.Warn("Error writing HTTP response message", zap.Error(err))
+	}
+}
+
+// sendLogs passes ld to the logs consumer, or to the coalescer when coalescing is enabled.
+func (r *splunkReceiver) sendLogs(ctx context.Context, ld plog.Logs) error {
+	if r.coalescer != nil {
+		r.coalescer.add(ld)
+		return nil
+	}
+	err := r.logsConsumer.ConsumeLogs(ctx, ld)
+	r.health.record(err)
+	return err
+}
+
 func (r *splunkReceiver) createResourceCustomizer(req *http.Request) func(resource pcommon.Resource) {
+	var accessTokenValue, channel string
+	var tokenAttributes map[string]string
 	if r.config.AccessTokenPassthrough {
-		accessToken := req.Header.Get("Authorization")
-		if strings.HasPrefix(accessToken, splunk.HECTokenHeader+" ") {
-			accessTokenValue := accessToken[len(splunk.HECTokenHeader)+1:]
-			return func(resource pcommon.Resource) {
-				resource.Attributes().PutStr(splunk.HecTokenLabel, accessTokenValue)
-			}
+		accessTokenValue = requestToken(req)
+	}
+	if len(r.config.Tokens) > 0 {
+		if token := requestToken(req); token != "" {
+			tokenAttributes = r.config.Tokens[token]
 		}
 	}
-	return nil
+	if r.config.ChannelPassthrough {
+		channel = requestChannel(req)
+	}
+	if accessTokenValue == "" && channel == "" && len(tokenAttributes) == 0 {
+		return nil
+	}
+	return func(resource pcommon.Resource) {
+		for key, value := range tokenAttributes {
+			resource.Attributes().PutStr(key, value)
+		}
+		if accessTokenValue != "" {
+			resource.Attributes().PutStr(splunk.HecTokenLabel, accessTokenValue)
+		}
+		if channel != "" {
+			resource.Attributes().PutStr(r.config.ChannelAttribute, channel)
+		}
+	}
+}
+
+// requestToken returns the HEC token of the request, read from the Authorization header.
+func requestToken(req *http.Request) string {
+	accessToken := req.Header.Get("Authorization")
+	if strings.HasPrefix(accessToken, splunk.HECTokenHeader+" ") {
+		return accessToken[len(splunk.HECTokenHeader)+1:]
+	}
+	return ""
+}
+
+// requestChannel returns the channel of the request, read from the X-Splunk-Request-Channel
+// header or, when the header is not set, from the "channel" query parameter.
+func requestChannel(req *http.Request) string {
+	if channel := req.Header.Get(httpChannelHeader); channel != "" {
+		return channel
+	}
+	return req.URL.Query().Get(channelQueryParam)
+}
+
+// rawMetadata returns the metadata shared by the lines of a raw request, set by its
+// query parameters or, when they are not set, by the configured defaults.
+func (r *splunkReceiver) rawMetadata(req *http.Request) *splunk.Event {
+	query := req.URL.Query()
+	param := func(key string, defaultValue string) string {
+		if value := query.Get(key); value != "" {
+			return value
+		}
+		return defaultValue
+	}
+	return &splunk.Event{
+		Host:       param(hostQueryParam, r.config.DefaultHost),
+		Source:     param(sourceQueryParam, r.config.DefaultSource),
+		SourceType: param(sourcetypeQueryParam, r.config.DefaultSourcetype),
+		Index:      param(indexQueryParam, r.config.DefaultIndex),
+	}
+}
+
+// rawTimestamp returns the timestamp set by the "time" query parameter of a raw request,
+// or zero when the parameter is not set.
+func rawTimestamp(req *http.Request) (pcommon.Timestamp, error) {
+	param := req.URL.Query().Get(timeQueryParam)
+	if param == "" {
+		return 0, nil
+	}
+	ts, ok := parseEpochSeconds(param)
+	if !ok {
+		return 0, errInvalidTimeQueryParam
+	}
+	return ts, nil
+}
+
+// hasBody reports whether the request carries a body. When the length is not
+// known up front, as with chunked transfer encoding, the first byte is read
+// ahead and req.Body is replaced so that it is still seen by the decoder.
+func hasBody(req *http.Request) bool {
+	if req.ContentLength >= 0 {
+		return req.ContentLength > 0
+	}
+	br := bufio.NewReader(req.Body)
+	if _, err := br.Peek(1); err != nil {
+		return false
+	}
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{br, req.Body}
+	return true
 }
 
 func (r *splunkReceiver) failRequest(
 	ctx context.Context,
 	resp http.ResponseWriter,
+	req *http.Request,
 	httpStatusCode int,
 	jsonResponse []byte,
 	numRecordsReceived int,
 	err error,
 ) {
-	resp.WriteHeader(httpStatusCode)
 	if len(jsonResponse) > 0 {
 		// The response needs to be written as a JSON string.
-		resp.Header().Add("Content-Type", "application/json")
-		_, writeErr := resp.Write(jsonResponse)
-		if writeErr != nil {
-			r.settings.Logger.Warn("Error writing HTTP response message", zap.Error(writeErr))
-		}
+		resp.Header().Add(httpContentTypeHeader, jsonContentType)
 	}
-
-	if r.metricsConsumer == nil {
-		r.obsrecv.EndLogsOp(ctx, typeStr, numRecordsReceived, err)
-	} else {
-		r.obsrecv.EndMetricsOp(ctx, typeStr, numRecordsReceived, err)
+	if writeErr := writeResponse(resp, req, httpStatusCode, jsonResponse); writeErr != nil {
+		r.settings.Logger.Warn("Error writing HTTP response message", zap.Error(writeErr))
 	}
 
+	r.endOp(ctx, numRecordsReceived, err)
+
 	if r.settings.Logger.Core().Enabled(zap.DebugLevel) {
 		msg := string(jsonResponse)
 		r.settings.Logger.Debug(
@@ -453,12 +913,77 @@ func (r *splunkReceiver) failRequest(
 	}
 }
 
+// writeResponse writes the status code and body of a response. Bodies of at
+// least minGzipResponseSize bytes are gzip compressed when the client accepts it,
+// so their responses vary with the Accept-Encoding header of the request.
+func writeResponse(resp http.ResponseWriter, req *http.Request, httpStatusCode int, body []byte) error {
+	if len(body) >= minGzipResponseSize {
+		resp.Header().Add(httpVaryHeader, httpAcceptEncodingHeader)
+	}
+	if len(body) < minGzipResponseSize || !acceptsGzip(req) {
+		resp.WriteHeader(httpStatusCode)
+		if len(body) == 0 {
+			return nil
+		}
+		_, err := resp.Write(body)
+		return err
+	}
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	if _, err := gzipWriter.Write(body); err != nil {
+		return err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return err
+	}
+	resp.Header().Set(httpContentEncodingHeader, gzipEncoding)
+	resp.WriteHeader(httpStatusCode)
+	_, err := resp.Write(buf.Bytes())
+	return err
+}
+
+// acceptsGzip reports whether the Accept-Encoding header of the request lists gzip
+// with a non-zero quality value.
+func acceptsGzip(req *http.Request) bool {
+	for _, header := range req.Header.Values(httpAcceptEncodingHeader) {
+		for _, coding := range strings.Split(header, ",") {
+			name, params, _ := strings.Cut(coding, ";")
+			if !strings.EqualFold(strings.TrimSpace(name), gzipEncoding) {
+				continue
+			}
+			params = strings.TrimSpace(params)
+			if !strings.HasPrefix(params, "q=") {
+				return true
+			}
+			quality, err := strconv.ParseFloat(params[len("q="):], 64)
+			return err == nil && quality > 0
+		}
+	}
+	return false
+}
+
+// endOp ends the obsreport operation started for the request, as a metrics
+// operation for a metrics receiver and as a logs operation otherwise.
+func (r *splunkReceiver) endOp(ctx context.Context, numRecordsReceived int, err error) {
+	if r.metricsConsumer == nil {
+		r.obsrecv.EndLogsOp(ctx, typeStr, numRecordsReceived, err)
+	} else {
+		r.obsrecv.EndMetricsOp(ctx, typeStr, numRecordsReceived, err)
+	}
+}
+
 func (r *splunkReceiver) handleHealthReq(writer http.ResponseWriter, _ *http.Request) {
+	if !r.health.healthy() {
+		writer.Header().Add(httpContentTypeHeader, jsonContentType)
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = writer.Write(r.responses.serverBusy)
+		return
+	}
 	writer.WriteHeader(200)
 }
 
-func initJSONResponse(s string) []byte {
-	respBody, err := jsoniter.Marshal(s)
+func initHECResponse(text string, code int) []byte {
+	respBody, err := jsoniter.Marshal(hecResponse{Text: text, Code: code})
 	if err != nil {
 		// This is to be used in initialization so panic here is fine.
 		panic(err)
@@ -466,6 +991,40 @@ func initJSONResponse(s string) []byte {
 	return respBody
 }
 
+// hecEventKeys are the keys of a HEC event decoded into the fields of splunk.Event.
+var hecEventKeys = map[string]bool{
+	"time": true, "host": true, "source": true, "sourcetype": true, "index": true, "event": true, "fields": true,
+}
+
+// applyEventKey sets the value of key in the JSON object rawEvent as the event of msg,
+// and the other keys of the object that are not HEC event keys as fields, unless a
+// field with the same key is already set. The whole object is used as the event when
+// key is absent.
+func applyEventKey(msg *splunk.Event, rawEvent []byte, key string) error {
+	var obj map[string]interface{}
+	if err := jsoniter.Unmarshal(rawEvent, &obj); err != nil {
+		return err
+	}
+	value, found := obj[key]
+	if !found {
+		msg.Event = obj
+		return nil
+	}
+	msg.Event = value
+	for k, v := range obj {
+		if k == key || hecEventKeys[k] {
+			continue
+		}
+		if msg.Fields == nil {
+			msg.Fields = make(map[string]interface{})
+		}
+		if _, exists := msg.Fields[k]; !exists {
+			msg.Fields[k] = v
+		}
+	}
+	return nil
+}
+
 func isFlatJSONField(field interface{}) bool {
 	switch value := field.(type) {
 	case map[string]interface{}:
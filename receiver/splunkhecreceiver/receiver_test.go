@@ -18,10 +18,12 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -31,6 +33,7 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/component/componenttest"
 	"go.opentelemetry.io/collector/config/confighttp"
@@ -38,9 +41,15 @@ import (
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/consumer/consumertest"
 	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/obsreport/obsreporttest"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/receiver/receivertest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/net/http2"
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/exporter/splunkhecexporter"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/common/testutil"
@@ -177,14 +186,14 @@ func Test_splunkhecReceiver_handleReq(t *testing.T) {
 	tests := []struct {
 		name           string
 		req            *http.Request
-		assertResponse func(t *testing.T, status int, body string)
+		assertResponse func(t *testing.T, status int, body interface{})
 	}{
 		{
 			name: "incorrect_method",
 			req:  httptest.NewRequest("PUT", "http://localhost/foo", nil),
-			assertResponse: func(t *testing.T, status int, body string) {
+			assertResponse: func(t *testing.T, status int, body interface{}) {
 				assert.Equal(t, http.StatusBadRequest, status)
-				assert.Equal(t, responseInvalidMethod, body)
+				assert.Equal(t, hecResponseBody(responseInvalidMethod, responseCodeInvalidDataFormat), body)
 			},
 		},
 		{
@@ -194,9 +203,9 @@ func Test_splunkhecReceiver_handleReq(t *testing.T) {
 				req.Header.Set("Content-Type", "application/not-json")
 				return req
 			}(),
-			assertResponse: func(t *testing.T, status int, body string) {
+			assertResponse: func(t *testing.T, status int, body interface{}) {
 				assert.Equal(t, http.StatusOK, status)
-				assert.Equal(t, responseOK, body)
+				assert.Equal(t, hecResponseBody(responseSuccess, responseCodeSuccess), body)
 			},
 		},
 		{
@@ -209,9 +218,9 @@ func Test_splunkhecReceiver_handleReq(t *testing.T) {
 				req := httptest.NewRequest("POST", "http://localhost/foo", bytes.NewReader(msgBytes))
 				return req
 			}(),
-			assertResponse: func(t *testing.T, status int, body string) {
+			assertResponse: func(t *testing.T, status int, body interface{}) {
 				assert.Equal(t, http.StatusBadRequest, status)
-				assert.Equal(t, responseErrUnsupportedMetricEvent, body)
+				assert.Equal(t, hecResponseBody(responseErrUnsupportedMetricEvent, responseCodeInvalidDataFormat), body)
 			},
 		},
 		{
@@ -221,9 +230,9 @@ func Test_splunkhecReceiver_handleReq(t *testing.T) {
 				req.Header.Set("Content-Encoding", "superzipper")
 				return req
 			}(),
-			assertResponse: func(t *testing.T, status int, body string) {
+			assertResponse: func(t *testing.T, status int, body interface{}) {
 				assert.Equal(t, http.StatusUnsupportedMediaType, status)
-				assert.Equal(t, responseInvalidEncoding, body)
+				assert.Equal(t, hecResponseBody(responseInvalidEncoding, responseCodeInvalidDataFormat), body)
 			},
 		},
 		{
@@ -232,9 +241,9 @@ func Test_splunkhecReceiver_handleReq(t *testing.T) {
 				req := httptest.NewRequest("POST", "http://localhost/foo", bytes.NewReader([]byte{1, 2, 3, 4}))
 				return req
 			}(),
-			assertResponse: func(t *testing.T, status int, body string) {
+			assertResponse: func(t *testing.T, status int, body interface{}) {
 				assert.Equal(t, http.StatusBadRequest, status)
-				assert.Equal(t, responseErrUnmarshalBody, body)
+				assert.Equal(t, hecResponseBody(responseErrUnmarshalBody, responseCodeInvalidDataFormat), body)
 			},
 		},
 		{
@@ -243,9 +252,9 @@ func Test_splunkhecReceiver_handleReq(t *testing.T) {
 				req := httptest.NewRequest("POST", "http://localhost/foo", bytes.NewReader(nil))
 				return req
 			}(),
-			assertResponse: func(t *testing.T, status int, body string) {
+			assertResponse: func(t *testing.T, status int, body interface{}) {
 				assert.Equal(t, http.StatusOK, status)
-				assert.Equal(t, responseOK, body)
+				assert.Equal(t, hecResponseBody(responseSuccess, responseCodeSuccess), body)
 			},
 		},
 		{
@@ -256,9 +265,37 @@ func Test_splunkhecReceiver_handleReq(t *testing.T) {
 				req := httptest.NewRequest("POST", "http://localhost/foo", bytes.NewReader(msgBytes))
 				return req
 			}(),
-			assertResponse: func(t *testing.T, status int, body string) {
+			assertResponse: func(t *testing.T, status int, body interface{}) {
 				assert.Equal(t, http.StatusOK, status)
-				assert.Equal(t, responseOK, body)
+				assert.Equal(t, hecResponseBody(responseSuccess, responseCodeSuccess), body)
+			},
+		},
+		{
+			name: "msg_accepted_json_charset",
+			req: func() *http.Request {
+				msgBytes, err := json.Marshal(splunkMsg)
+				require.NoError(t, err)
+				req := httptest.NewRequest("POST", "http://localhost/foo", bytes.NewReader(msgBytes))
+				req.Header.Set("Content-Type", "application/json; charset=utf-8")
+				return req
+			}(),
+			assertResponse: func(t *testing.T, status int, body interface{}) {
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, hecResponseBody(responseSuccess, responseCodeSuccess), body)
+			},
+		},
+		{
+			name: "msg_accepted_json_whitespace_and_uppercase",
+			req: func() *http.Request {
+				msgBytes, err := json.Marshal(splunkMsg)
+				require.NoError(t, err)
+				req := httptest.NewRequest("POST", "http://localhost/foo", bytes.NewReader(msgBytes))
+				req.Header.Set("Content-Type", " Application/JSON ;  Charset=UTF-8 ")
+				return req
+			}(),
+			assertResponse: func(t *testing.T, status int, body interface{}) {
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, hecResponseBody(responseSuccess, responseCodeSuccess), body)
 			},
 		},
 		{
@@ -277,9 +314,9 @@ func Test_splunkhecReceiver_handleReq(t *testing.T) {
 				req.Header.Set("Content-Encoding", "gzip")
 				return req
 			}(),
-			assertResponse: func(t *testing.T, status int, body string) {
+			assertResponse: func(t *testing.T, status int, body interface{}) {
 				assert.Equal(t, http.StatusOK, status)
-				assert.Equal(t, responseOK, body)
+				assert.Equal(t, hecResponseBody(responseSuccess, responseCodeSuccess), body)
 			},
 		},
 		{
@@ -292,9 +329,9 @@ func Test_splunkhecReceiver_handleReq(t *testing.T) {
 				req.Header.Set("Content-Encoding", "gzip")
 				return req
 			}(),
-			assertResponse: func(t *testing.T, status int, body string) {
+			assertResponse: func(t *testing.T, status int, body interface{}) {
 				assert.Equal(t, http.StatusBadRequest, status)
-				assert.Equal(t, responseErrGzipReader, body)
+				assert.Equal(t, hecResponseBody(responseErrGzipReader, responseCodeInvalidDataFormat), body)
 			},
 		},
 	}
@@ -313,10 +350,10 @@ func Test_splunkhecReceiver_handleReq(t *testing.T) {
 			respBytes, err := io.ReadAll(resp.Body)
 			assert.NoError(t, err)
 
-			var bodyStr string
-			assert.NoError(t, json.Unmarshal(respBytes, &bodyStr))
+			var body interface{}
+			assert.NoError(t, json.Unmarshal(respBytes, &body))
 
-			tt.assertResponse(t, resp.StatusCode, bodyStr)
+			tt.assertResponse(t, resp.StatusCode, body)
 		})
 	}
 }
@@ -340,11 +377,11 @@ func Test_consumer_err(t *testing.T) {
 	respBytes, err := io.ReadAll(resp.Body)
 	assert.NoError(t, err)
 
-	var bodyStr string
-	assert.NoError(t, json.Unmarshal(respBytes, &bodyStr))
+	var body interface{}
+	assert.NoError(t, json.Unmarshal(respBytes, &body))
 
 	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
-	assert.Equal(t, "Internal Server Error", bodyStr)
+	assert.Equal(t, hecResponseBody("Internal Server Error", responseCodeInternalServerError), body)
 }
 
 func Test_consumer_err_metrics(t *testing.T) {
@@ -367,11 +404,1027 @@ func Test_consumer_err_metrics(t *testing.T) {
 	respBytes, err := io.ReadAll(resp.Body)
 	assert.NoError(t, err)
 
-	var bodyStr string
-	assert.NoError(t, json.Unmarshal(respBytes, &bodyStr))
+	var body interface{}
+	assert.NoError(t, json.Unmarshal(respBytes, &body))
 
 	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
-	assert.Equal(t, "Internal Server Error", bodyStr)
+	assert.Equal(t, hecResponseBody("Internal Server Error", responseCodeInternalServerError), body)
+}
+
+func Test_splunkhecReceiver_chunkedBody(t *testing.T) {
+	currentTime := float64(time.Now().UnixNano()) / 1e6
+	msgBytes, err := json.Marshal(buildSplunkHecMsg(currentTime, 3))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		path      string
+		body      []byte
+		wantCount int
+	}{
+		{
+			name:      "event",
+			path:      "http://localhost/services/collector",
+			body:      append(append([]byte{}, msgBytes...), msgBytes...),
+			wantCount: 2,
+		},
+		{
+			name:      "raw",
+			path:      "http://localhost/services/collector/raw",
+			body:      []byte("line one\nline two\n"),
+			wantCount: 2,
+		},
+		{
+			name:      "empty_event",
+			path:      "http://localhost/services/collector",
+			wantCount: 0,
+		},
+		{
+			name:      "empty_raw",
+			path:      "http://localhost/services/collector/raw",
+			wantCount: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createDefaultConfig().(*Config)
+			sink := new(consumertest.LogsSink)
+			rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, sink)
+			require.NoError(t, err)
+			r := rcv.(*splunkReceiver)
+
+			// io.MultiReader hides the length, so the request is sent without a ContentLength.
+			req := httptest.NewRequest("POST", tt.path, io.MultiReader(bytes.NewReader(tt.body)))
+			req.TransferEncoding = []string{"chunked"}
+			require.Equal(t, int64(-1), req.ContentLength)
+
+			w := httptest.NewRecorder()
+			if strings.HasSuffix(tt.path, "/raw") {
+				r.handleRawReq(w, req)
+			} else {
+				r.handleReq(w, req)
+			}
+
+			assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+			assert.Equal(t, tt.wantCount, sink.LogRecordCount())
+			if tt.wantCount == 0 {
+				assert.Empty(t, sink.AllLogs())
+			}
+		})
+	}
+}
+
+func Test_splunkhecReceiver_textContentType(t *testing.T) {
+	currentTime := float64(time.Now().UnixNano()) / 1e6
+	msgBytes, err := json.Marshal(buildSplunkHecMsg(currentTime, 3))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		contentType  string
+		allowedTypes []string
+		body         []byte
+		wantBodies   []string
+	}{
+		{
+			name:         "text_plain_allowed",
+			contentType:  "text/plain",
+			allowedTypes: []string{"application/json", "text/plain"},
+			body:         []byte("line one\nline two\n"),
+			wantBodies:   []string{"line one", "line two"},
+		},
+		{
+			name:         "text_plain_with_charset_allowed",
+			contentType:  "Text/Plain; charset=utf-8",
+			allowedTypes: []string{"application/json", "text/plain"},
+			body:         []byte("line one\n"),
+			wantBodies:   []string{"line one"},
+		},
+		{
+			name:         "text_plain_not_allowed_decoded_as_json",
+			contentType:  "text/plain",
+			allowedTypes: []string{"application/json"},
+			body:         msgBytes,
+			wantBodies:   []string{"foo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createDefaultConfig().(*Config)
+			config.AllowedContentTypes = tt.allowedTypes
+			sink := new(consumertest.LogsSink)
+			rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, sink)
+			require.NoError(t, err)
+			r := rcv.(*splunkReceiver)
+
+			req := httptest.NewRequest("POST", "http://localhost/services/collector", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			r.handleReq(w, req)
+
+			resp := w.Result()
+			respBytes, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, `{"text":"Success","code":0}`, string(respBytes))
+
+			require.Len(t, sink.AllLogs(), 1)
+			logRecords := sink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+			require.Equal(t, len(tt.wantBodies), logRecords.Len())
+			for i, want := range tt.wantBodies {
+				assert.Equal(t, want, logRecords.At(i).Body().AsString())
+			}
+		})
+	}
+
+	t.Run("metrics_receiver_rejects_text", func(t *testing.T) {
+		config := createDefaultConfig().(*Config)
+		config.AllowedContentTypes = []string{"text/plain"}
+		sink := new(consumertest.MetricsSink)
+		rcv, err := newMetricsReceiver(receivertest.NewNopCreateSettings(), *config, sink)
+		require.NoError(t, err)
+		r := rcv.(*splunkReceiver)
+
+		req := httptest.NewRequest("POST", "http://localhost/services/collector", strings.NewReader("line one\n"))
+		req.Header.Set("Content-Type", "text/plain")
+		w := httptest.NewRecorder()
+		r.handleReq(w, req)
+
+		resp := w.Result()
+		respBytes, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var body interface{}
+		require.NoError(t, json.Unmarshal(respBytes, &body))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, hecResponseBody(responseErrUnsupportedLogEvent, responseCodeInvalidDataFormat), body)
+		assert.Equal(t, 0, sink.DataPointCount())
+	})
+}
+
+func Test_splunkhecReceiver_successStatus(t *testing.T) {
+	currentTime := float64(time.Now().UnixNano()) / 1e6
+	msgBytes, err := json.Marshal(buildSplunkHecMsg(currentTime, 3))
+	require.NoError(t, err)
+	metricBytes, err := json.Marshal(buildSplunkHecMetricsMsg(currentTime, 1.0, 3))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		metrics bool
+		body    []byte
+	}{
+		{name: "logs_empty"},
+		{name: "logs_non_empty", body: msgBytes},
+		{name: "metrics_empty", metrics: true},
+		{name: "metrics_non_empty", metrics: true, body: metricBytes},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testTel, err := obsreporttest.SetupTelemetry(component.NewID(typeStr))
+			require.NoError(t, err)
+			defer func() {
+				require.NoError(t, testTel.Shutdown(context.Background()))
+			}()
+
+			config := createDefaultConfig().(*Config)
+			var rcv interface{}
+			if tt.metrics {
+				rcv, err = newMetricsReceiver(testTel.ToReceiverCreateSettings(), *config, consumertest.NewNop())
+			} else {
+				rcv, err = newLogsReceiver(testTel.ToReceiverCreateSettings(), *config, consumertest.NewNop())
+			}
+			require.NoError(t, err)
+			r := rcv.(*splunkReceiver)
+
+			w := httptest.NewRecorder()
+			r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", bytes.NewReader(tt.body)))
+
+			resp := w.Result()
+			respBytes, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, `{"text":"Success","code":0}`, string(respBytes))
+			// Every request, including empty ones, ends the obsreport operation it started.
+			assert.Len(t, testTel.SpanRecorder.Ended(), 1)
+		})
+	}
+}
+
+func Test_splunkhecReceiver_rawLinesConsumerError(t *testing.T) {
+	testTel, err := obsreporttest.SetupTelemetry(component.NewID(typeStr))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, testTel.Shutdown(context.Background()))
+	}()
+
+	config := createDefaultConfig().(*Config)
+	config.AllowedContentTypes = []string{"application/json", "text/plain"}
+	rcv, err := newLogsReceiver(testTel.ToReceiverCreateSettings(), *config, consumertest.NewErr(errors.New("consumer failed")))
+	require.NoError(t, err)
+	r := rcv.(*splunkReceiver)
+
+	req := httptest.NewRequest("POST", "http://localhost/services/collector", strings.NewReader("first\nsecond\n"))
+	req.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	r.handleReq(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	// The refused records are only counted once.
+	require.NoError(t, testTel.CheckReceiverLogs("http", 0, 2))
+}
+
+func Test_splunkhecReceiver_rawReservedKeys(t *testing.T) {
+	config := createDefaultConfig().(*Config)
+	sink := new(consumertest.LogsSink)
+	rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, sink)
+	require.NoError(t, err)
+	r := rcv.(*splunkReceiver)
+
+	body := "plain line\n" + `{"_raw":"embedded line","__time":2}` + "\n"
+	req := httptest.NewRequest("POST", "http://localhost/services/collector/raw?time=1", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	r.handleRawReq(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	require.Len(t, sink.AllLogs(), 1)
+	logRecords := sink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	require.Equal(t, 2, logRecords.Len())
+	assert.Equal(t, "plain line", logRecords.At(0).Body().Str())
+	assert.Equal(t, pcommon.Timestamp(1e9), logRecords.At(0).Timestamp())
+	assert.Equal(t, "embedded line", logRecords.At(1).Body().Str())
+	assert.Equal(t, pcommon.Timestamp(2e9), logRecords.At(1).Timestamp())
+
+	req = httptest.NewRequest("POST", "http://localhost/services/collector/raw?time=yesterday", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	r.handleRawReq(w, req)
+
+	resp := w.Result()
+	respBytes, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	var respBody interface{}
+	require.NoError(t, json.Unmarshal(respBytes, &respBody))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, hecResponseBody(responseInvalidTimeQueryParam, responseCodeInvalidDataFormat), respBody)
+	assert.Len(t, sink.AllLogs(), 1)
+}
+
+func Test_splunkhecReceiver_keepRawEvent(t *testing.T) {
+	rawEvents := []string{
+		`{"time": 1.5, "event":"first",  "fields":{"k0":"v0"}}`,
+		`{ "event" : { "message" : "second" }, "host":"myhost" }`,
+	}
+
+	config := createDefaultConfig().(*Config)
+	config.KeepRawEvent = true
+	sink := new(consumertest.LogsSink)
+	rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, sink)
+	require.NoError(t, err)
+	r := rcv.(*splunkReceiver)
+
+	req := httptest.NewRequest("POST", "http://localhost/services/collector", strings.NewReader(strings.Join(rawEvents, "\n")))
+	w := httptest.NewRecorder()
+	r.handleReq(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	require.Len(t, sink.AllLogs(), 1)
+	require.Equal(t, 2, sink.LogRecordCount())
+	var got []string
+	for i := 0; i < sink.AllLogs()[0].ResourceLogs().Len(); i++ {
+		logRecords := sink.AllLogs()[0].ResourceLogs().At(i).ScopeLogs().At(0).LogRecords()
+		for j := 0; j < logRecords.Len(); j++ {
+			raw, ok := logRecords.At(j).Attributes().Get("splunk.raw")
+			require.True(t, ok)
+			got = append(got, raw.Str())
+		}
+	}
+	assert.Equal(t, rawEvents, got)
+}
+
+func Test_splunkhecReceiver_unhealthyConsumer(t *testing.T) {
+	currentTime := float64(time.Now().UnixNano()) / 1e6
+	msgBytes, err := json.Marshal(buildSplunkHecMsg(currentTime, 3))
+	require.NoError(t, err)
+
+	config := createDefaultConfig().(*Config)
+	config.FailureThreshold = 2
+	config.RecoveryWindow = time.Hour
+	rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, consumertest.NewErr(errors.New("bad consumer")))
+	require.NoError(t, err)
+	r := rcv.(*splunkReceiver)
+
+	post := func() *http.Response {
+		w := httptest.NewRecorder()
+		r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", bytes.NewReader(msgBytes)))
+		return w.Result()
+	}
+	health := func() *http.Response {
+		w := httptest.NewRecorder()
+		r.handleHealthReq(w, httptest.NewRequest("GET", "http://localhost/services/collector/health", nil))
+		return w.Result()
+	}
+
+	assert.Equal(t, http.StatusInternalServerError, post().StatusCode)
+	assert.Equal(t, http.StatusOK, health().StatusCode)
+	assert.Equal(t, http.StatusInternalServerError, post().StatusCode)
+
+	for _, resp := range []*http.Response{post(), health()} {
+		respBytes, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var body interface{}
+		require.NoError(t, json.Unmarshal(respBytes, &body))
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, hecResponseBody(responseErrServerBusy, responseCodeServerBusy), body)
+	}
+
+	w := httptest.NewRecorder()
+	r.handleRawReq(w, httptest.NewRequest("POST", "http://localhost/services/collector/raw", strings.NewReader("line\n")))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Result().StatusCode)
+}
+
+func Test_splunkhecReceiver_coalesce(t *testing.T) {
+	currentTime := float64(time.Now().UnixNano()) / 1e6
+	msgBytes, err := json.Marshal(buildSplunkHecMsg(currentTime, 3))
+	require.NoError(t, err)
+
+	config := createDefaultConfig().(*Config)
+	config.Coalesce = CoalesceConfig{Enabled: true, MaxRecords: 100, Timeout: 100 * time.Millisecond}
+	sink := new(consumertest.LogsSink)
+	rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, sink)
+	require.NoError(t, err)
+	r := rcv.(*splunkReceiver)
+
+	w := httptest.NewRecorder()
+	r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", bytes.NewReader(msgBytes)))
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	w = httptest.NewRecorder()
+	r.handleRawReq(w, httptest.NewRequest("POST", "http://localhost/services/collector/raw", strings.NewReader("first\nsecond\n")))
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Len(t, sink.AllLogs(), 0, "requests are acknowledged before the logs are sent")
+
+	assert.Eventually(t, func() bool { return len(sink.AllLogs()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 3, sink.LogRecordCount())
+	assert.Equal(t, 2, sink.AllLogs()[0].ResourceLogs().Len())
+}
+
+func Test_splunkhecReceiver_dedup(t *testing.T) {
+	body := `{"event":"first","fields":{"id":"1"}}
+{"event":"retried","fields":{"id":"1"}}
+{"event":"no id"}`
+
+	config := createDefaultConfig().(*Config)
+	config.Dedup.Enabled = true
+	sink := new(consumertest.LogsSink)
+	rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, sink)
+	require.NoError(t, err)
+	r := rcv.(*splunkReceiver)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", strings.NewReader(body)))
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	}
+
+	require.Len(t, sink.AllLogs(), 2)
+	var got []string
+	for _, ld := range sink.AllLogs() {
+		for i := 0; i < ld.ResourceLogs().Len(); i++ {
+			logRecords := ld.ResourceLogs().At(i).ScopeLogs().At(0).LogRecords()
+			for j := 0; j < logRecords.Len(); j++ {
+				got = append(got, logRecords.At(j).Body().Str())
+			}
+		}
+	}
+	assert.Equal(t, []string{"first", "no id", "no id"}, got)
+}
+
+func Test_splunkhecReceiver_dedupRetryAfterConsumerError(t *testing.T) {
+	body := `{"event":"first","fields":{"id":"1"}}`
+
+	config := createDefaultConfig().(*Config)
+	config.Dedup.Enabled = true
+	sink := new(consumertest.LogsSink)
+	failing := true
+	next, err := consumer.NewLogs(func(ctx context.Context, ld plog.Logs) error {
+		if failing {
+			return errors.New("consumer failed")
+		}
+		return sink.ConsumeLogs(ctx, ld)
+	})
+	require.NoError(t, err)
+	rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, next)
+	require.NoError(t, err)
+	r := rcv.(*splunkReceiver)
+
+	w := httptest.NewRecorder()
+	r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", strings.NewReader(body)))
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+
+	// The retried events were not consumed, so they are not duplicates.
+	failing = false
+	w = httptest.NewRecorder()
+	r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	require.Equal(t, 1, sink.LogRecordCount())
+
+	// Once consumed, they are.
+	w = httptest.NewRecorder()
+	r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, 1, sink.LogRecordCount())
+}
+
+func Test_splunkhecReceiver_dropMetricEvents(t *testing.T) {
+	view.Unregister(MetricViews()...)
+	views := MetricViews()
+	require.NoError(t, view.Register(views...))
+	defer view.Unregister(views...)
+
+	body := `{"event":"first"}
+{"event":"metric","fields":{"metric_name:cpu":1}}
+{"event":"second"}`
+
+	config := createDefaultConfig().(*Config)
+	config.DropMetricEvents = true
+	config.KeepRawEvent = true
+	sink := new(consumertest.LogsSink)
+	rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, sink)
+	require.NoError(t, err)
+	r := rcv.(*splunkReceiver)
+
+	w := httptest.NewRecorder()
+	r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	require.Len(t, sink.AllLogs(), 1)
+	logRecords := sink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	require.Equal(t, 2, logRecords.Len())
+	for i, want := range []string{"first", "second"} {
+		assert.Equal(t, want, logRecords.At(i).Body().Str())
+		raw, ok := logRecords.At(i).Attributes().Get("splunk.raw")
+		require.True(t, ok)
+		assert.Equal(t, fmt.Sprintf(`{"event":%q}`, want), raw.Str())
+	}
+
+	viewData, err := view.RetrieveData(statDroppedMetricEvents.Name())
+	require.NoError(t, err)
+	require.Len(t, viewData, 1)
+	assert.Equal(t, float64(1), viewData[0].Data.(*view.SumData).Value)
+}
+
+func Test_splunkhecReceiver_dropMetricEventsOnly(t *testing.T) {
+	body := `{"event":"metric","fields":{"metric_name:cpu":1}}
+{"event":"metric","fields":{"metric_name:mem":2}}`
+
+	config := createDefaultConfig().(*Config)
+	config.DropMetricEvents = true
+	calls := 0
+	nextConsumer, err := consumer.NewLogs(func(context.Context, plog.Logs) error {
+		calls++
+		return nil
+	})
+	require.NoError(t, err)
+	rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, nextConsumer)
+	require.NoError(t, err)
+	r := rcv.(*splunkReceiver)
+
+	w := httptest.NewRecorder()
+	r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, r.responses.success, w.Body.Bytes())
+	// Batches of metric events only do not pass empty batches to the next consumer.
+	assert.Equal(t, 0, calls)
+}
+
+func Test_splunkhecReceiver_dropEmptyEvents(t *testing.T) {
+	view.Unregister(MetricViews()...)
+	views := MetricViews()
+	require.NoError(t, view.Register(views...))
+	defer view.Unregister(views...)
+
+	body := `{"event":"first"}
+{"event":""}
+{"event":" \n\t"}
+{"event":null,"fields":{"heartbeat":"1"}}
+{"event":"second"}`
+
+	config := createDefaultConfig().(*Config)
+	config.DropEmptyEvents = true
+	config.KeepRawEvent = true
+	sink := new(consumertest.LogsSink)
+	rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, sink)
+	require.NoError(t, err)
+	r := rcv.(*splunkReceiver)
+
+	w := httptest.NewRecorder()
+	r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	require.Len(t, sink.AllLogs(), 1)
+	logRecords := sink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	require.Equal(t, 2, logRecords.Len())
+	for i, want := range []string{"first", "second"} {
+		assert.Equal(t, want, logRecords.At(i).Body().Str())
+		raw, ok := logRecords.At(i).Attributes().Get("splunk.raw")
+		require.True(t, ok)
+		assert.Equal(t, fmt.Sprintf(`{"event":%q}`, want), raw.Str())
+	}
+
+	viewData, err := view.RetrieveData(statDroppedEmptyEvents.Name())
+	require.NoError(t, err)
+	require.Len(t, viewData, 1)
+	assert.Equal(t, float64(3), viewData[0].Data.(*view.SumData).Value)
+}
+
+func Test_splunkhecReceiver_dropEmptyEventsOnly(t *testing.T) {
+	body := `{"event":""}
+{"event":" \n\t"}`
+
+	config := createDefaultConfig().(*Config)
+	config.DropEmptyEvents = true
+	calls := 0
+	nextConsumer, err := consumer.NewLogs(func(context.Context, plog.Logs) error {
+		calls++
+		return nil
+	})
+	require.NoError(t, err)
+	rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, nextConsumer)
+	require.NoError(t, err)
+	r := rcv.(*splunkReceiver)
+
+	w := httptest.NewRecorder()
+	r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, r.responses.success, w.Body.Bytes())
+	// The heartbeat requests do not pass empty batches to the next consumer.
+	assert.Equal(t, 0, calls)
+}
+
+func Test_splunkhecReceiver_timeNanosField(t *testing.T) {
+	body := `{"time":1675245600.123,"event":"integer","fields":{"timestamp_ns":1675245600123456789}}
+{"time":1675245600.123,"event":"rfc3339","fields":{"timestamp_ns":"2023-02-01T10:00:00.987654321Z"}}
+{"time":1675245600.123,"event":"absent"}`
+
+	for _, parallel := range []bool{false, true} {
+		t.Run(fmt.Sprintf("parallel_decode_%v", parallel), func(t *testing.T) {
+			config := createDefaultConfig().(*Config)
+			config.TimeNanosField = "fields.timestamp_ns"
+			config.ParallelDecode.Enabled = parallel
+			config.ParallelDecode.MinContentLength = 0
+			sink := new(consumertest.LogsSink)
+			rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, sink)
+			require.NoError(t, err)
+			r := rcv.(*splunkReceiver)
+
+			w := httptest.NewRecorder()
+			r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", strings.NewReader(body)))
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			require.Len(t, sink.AllLogs(), 1)
+			logRecords := sink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+			require.Equal(t, 3, logRecords.Len())
+			assert.Equal(t, pcommon.Timestamp(1675245600123456789), logRecords.At(0).Timestamp())
+			timestampNanos, ok := logRecords.At(0).Attributes().Get("timestamp_ns")
+			require.True(t, ok)
+			assert.Equal(t, int64(1675245600123456789), timestampNanos.Int())
+			assert.Equal(t, pcommon.Timestamp(1675245600987654321), logRecords.At(1).Timestamp())
+			eventTime := 1675245600.123
+			assert.Equal(t, pcommon.Timestamp(eventTime*1e9), logRecords.At(2).Timestamp())
+		})
+	}
+}
+
+func Test_splunkhecReceiver_requestStats(t *testing.T) {
+	view.Unregister(MetricViews()...)
+	views := MetricViews()
+	require.NoError(t, view.Register(views...))
+	defer view.Unregister(views...)
+
+	msgBytes, err := json.Marshal(buildSplunkHecMsg(float64(time.Now().UnixNano())/1e6, 3))
+	require.NoError(t, err)
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, err = gzipWriter.Write(msgBytes)
+	require.NoError(t, err)
+	require.NoError(t, gzipWriter.Close())
+
+	rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *createDefaultConfig().(*Config), consumertest.NewNop())
+	require.NoError(t, err)
+	r := rcv.(*splunkReceiver)
+
+	req := httptest.NewRequest("POST", "http://localhost/services/collector", bytes.NewReader(gzipped.Bytes()))
+	req.Header.Set("Content-Encoding", "gzip")
+	r.handleReq(httptest.NewRecorder(), req)
+	r.handleRawReq(httptest.NewRecorder(), httptest.NewRequest("POST", "http://localhost/services/collector/raw", strings.NewReader("line\n")))
+	r.handleReq(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost/services/collector", nil))
+
+	rows := func(name string) map[string]*view.DistributionData {
+		viewData, errRetrieve := view.RetrieveData(name)
+		require.NoError(t, errRetrieve)
+		got := map[string]*view.DistributionData{}
+		for _, row := range viewData {
+			for _, tg := range row.Tags {
+				if tg.Key == tagStatusClass {
+					got[tg.Value] = row.Data.(*view.DistributionData)
+				}
+			}
+		}
+		return got
+	}
+
+	latency := rows(statRequestLatency.Name())
+	require.Len(t, latency, 2)
+	assert.Equal(t, int64(2), latency["2xx"].Count)
+	assert.Equal(t, int64(1), latency["4xx"].Count)
+
+	payloadSize := rows(statPayloadSize.Name())
+	require.Len(t, payloadSize, 2)
+	assert.Equal(t, float64(len(msgBytes)+len("line\n")), payloadSize["2xx"].Sum())
+	assert.Equal(t, float64(0), payloadSize["4xx"].Sum())
+}
+
+func Test_splunkhecReceiver_maxDecompressionRatio(t *testing.T) {
+	// 32MiB of identical events compress to a few hundred KiB.
+	var body bytes.Buffer
+	for body.Len() < 32<<20 {
+		body.WriteString(`{"event":"the same event, over and over again"}` + "\n")
+	}
+	gzipped := gzipBytes(t, body.Bytes())
+
+	tests := []struct {
+		name     string
+		path     string
+		maxRatio int
+		handle   func(r *splunkReceiver) http.HandlerFunc
+		status   int
+	}{
+		{
+			name:     "event_rejected",
+			maxRatio: 20,
+			handle:   func(r *splunkReceiver) http.HandlerFunc { return r.handleReq },
+			status:   http.StatusBadRequest,
+		},
+		{
+			name:     "raw_rejected",
+			maxRatio: 20,
+			handle:   func(r *splunkReceiver) http.HandlerFunc { return r.handleRawReq },
+			status:   http.StatusBadRequest,
+		},
+		{
+			name:   "event_unlimited",
+			handle: func(r *splunkReceiver) http.HandlerFunc { return r.handleReq },
+			status: http.StatusOK,
+		},
+		{
+			name:     "event_below_limit",
+			maxRatio: 10000,
+			handle:   func(r *splunkReceiver) http.HandlerFunc { return r.handleReq },
+			status:   http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createDefaultConfig().(*Config)
+			config.MaxDecompressionRatio = tt.maxRatio
+			sink := new(consumertest.LogsSink)
+			rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, sink)
+			require.NoError(t, err)
+			r := rcv.(*splunkReceiver)
+
+			req := httptest.NewRequest("POST", "http://localhost/services/collector", bytes.NewReader(gzipped))
+			req.Header.Set("Content-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			tt.handle(r)(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusBadRequest {
+				assert.Equal(t, r.responses.gzipReader, w.Body.Bytes())
+				assert.Empty(t, sink.AllLogs())
+			} else {
+				assert.Equal(t, strings.Count(body.String(), "\n"), sink.LogRecordCount())
+			}
+		})
+	}
+}
+
+func Test_splunkhecReceiver_maxDecompressionRatioServer(t *testing.T) {
+	// Through the server started by Start, confighttp decompresses the body before the handlers.
+	var body bytes.Buffer
+	for body.Len() < 32<<20 {
+		body.WriteString(`{"event":"the same event, over and over again"}` + "\n")
+	}
+	gzipped := gzipBytes(t, body.Bytes())
+
+	addr := testutil.GetAvailableLocalAddress(t)
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = addr
+	cfg.MaxDecompressionRatio = 20
+	sink := new(consumertest.LogsSink)
+	r, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, r.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		require.NoError(t, r.Shutdown(context.Background()))
+	}()
+
+	for _, path := range []string{"/services/collector", cfg.RawPath} {
+		req, err := http.NewRequest("POST", fmt.Sprintf("http://%s%s", addr, path), bytes.NewReader(gzipped))
+		require.NoError(t, err)
+		req.Header.Set("Content-Encoding", "gzip")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, path)
+		assert.Equal(t, r.(*splunkReceiver).responses.gzipReader, respBody, path)
+	}
+	assert.Empty(t, sink.AllLogs())
+}
+
+func Test_splunkhecReceiver_eventKey(t *testing.T) {
+	body := `{"time":1.5,"host":"myhost","message":"hello","level":"info","fields":{"level":"warn"}}
+{"host":"myhost","payload":"no message key"}`
+
+	config := createDefaultConfig().(*Config)
+	config.EventKey = "message"
+	sink := new(consumertest.LogsSink)
+	rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, sink)
+	require.NoError(t, err)
+	r := rcv.(*splunkReceiver)
+
+	w := httptest.NewRecorder()
+	r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	require.Len(t, sink.AllLogs(), 1)
+	rl := sink.AllLogs()[0].ResourceLogs().At(0)
+	host, ok := rl.Resource().Attributes().Get("host.name")
+	require.True(t, ok)
+	assert.Equal(t, "myhost", host.Str())
+	logRecords := rl.ScopeLogs().At(0).LogRecords()
+	require.Equal(t, 2, logRecords.Len())
+
+	assert.Equal(t, "hello", logRecords.At(0).Body().Str())
+	assert.Equal(t, pcommon.Timestamp(1.5*1e9), logRecords.At(0).Timestamp())
+	assert.Equal(t, map[string]interface{}{"level": "warn"}, logRecords.At(0).Attributes().AsRaw(), "fields take precedence")
+
+	assert.Equal(t, map[string]interface{}{"host": "myhost", "payload": "no message key"}, logRecords.At(1).Body().Map().AsRaw())
+	assert.Equal(t, 0, logRecords.At(1).Attributes().Len())
+}
+
+func Test_splunkhecReceiver_logFailedRequests(t *testing.T) {
+	body := `{"event":"ok","fields":{"token":"secret"}} {"event":"bad",}`
+
+	config := createDefaultConfig().(*Config)
+	config.LogFailedRequests = true
+	config.RedactedFields = []string{"token"}
+	core, logs := observer.New(zapcore.WarnLevel)
+	settings := receivertest.NewNopCreateSettings()
+	settings.Logger = zap.New(core)
+	sink := new(consumertest.LogsSink)
+	rcv, err := newLogsReceiver(settings, *config, sink)
+	require.NoError(t, err)
+	r := rcv.(*splunkReceiver)
+
+	w := httptest.NewRecorder()
+	r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", strings.NewReader(body)))
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+
+	entries := logs.FilterMessage("Failed to decode request body").All()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	snippet := `{"event":"ok","fields":{"token":"REDACTED"}} {"event":"bad",}`
+	assert.Equal(t, snippet, fields["snippet"])
+	assert.Equal(t, int64(len(body)), fields["body_size"])
+	assert.Equal(t, int64(len(body)), fields["offset"])
+}
+
+func Test_splunkhecReceiver_responsesOverride(t *testing.T) {
+	currentTime := float64(time.Now().UnixNano()) / 1e6
+	msgBytes, err := json.Marshal(buildSplunkHecMsg(currentTime, 3))
+	require.NoError(t, err)
+	nestedMsg := buildSplunkHecMsg(currentTime, 3)
+	nestedMsg.Fields["nested"] = map[string]interface{}{}
+	nestedBytes, err := json.Marshal(nestedMsg)
+	require.NoError(t, err)
+
+	config := createDefaultConfig().(*Config)
+	config.Responses = map[string]string{
+		"internal_server_error": `Request "failed"`,
+		"indexed_fields":        "Invalid fields",
+	}
+	rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, consumertest.NewErr(errors.New("bad consumer")))
+	require.NoError(t, err)
+	r := rcv.(*splunkReceiver)
+
+	w := httptest.NewRecorder()
+	r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", bytes.NewReader(msgBytes)))
+	var body interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, hecResponseBody(`Request "failed"`, responseCodeInternalServerError), body)
+
+	w = httptest.NewRecorder()
+	r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", bytes.NewReader(nestedBytes)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"text":"Invalid fields","code":15,"invalid-event-number":0}`, w.Body.String())
+
+	// Categories without an override keep the default text.
+	w = httptest.NewRecorder()
+	r.handleReq(w, httptest.NewRequest("GET", "http://localhost/services/collector", nil))
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, hecResponseBody(responseInvalidMethod, responseCodeInvalidDataFormat), body)
+
+	config.Responses = map[string]string{"success": "Accepted"}
+	rcv, err = newLogsReceiver(receivertest.NewNopCreateSettings(), *config, consumertest.NewNop())
+	require.NoError(t, err)
+	r = rcv.(*splunkReceiver)
+	w = httptest.NewRecorder()
+	r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", bytes.NewReader(msgBytes)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"text":"Accepted","code":0}`, w.Body.String())
+}
+
+func Test_splunkhecReceiver_eventStatus(t *testing.T) {
+	body := `{"event":"first"}
+{"event":"nested fields","fields":{"nested":{"a":1}}}
+{"event":"metric","fields":{"metric_name:cpu":1}}
+{"event":"second"}
+{"event":`
+
+	tests := []struct {
+		name     string
+		consumer consumer.Logs
+		req      func() *http.Request
+		want     string
+	}{
+		{
+			name:     "accept_header",
+			consumer: consumertest.NewNop(),
+			req: func() *http.Request {
+				req := httptest.NewRequest("POST", "http://localhost/services/collector", strings.NewReader(body))
+				req.Header.Set("Accept", "application/json, application/x-ndjson; q=0.9")
+				return req
+			},
+			want: `{"index":0,"status":"ok"}
+{"index":1,"status":"error"}
+{"index":2,"status":"error"}
+{"index":3,"status":"ok"}
+{"index":4,"status":"error"}
+`,
+		},
+		{
+			name:     "query_parameter",
+			consumer: consumertest.NewNop(),
+			req: func() *http.Request {
+				return httptest.NewRequest("POST", "http://localhost/services/collector?event_status=true", strings.NewReader(body))
+			},
+			want: `{"index":0,"status":"ok"}
+{"index":1,"status":"error"}
+{"index":2,"status":"error"}
+{"index":3,"status":"ok"}
+{"index":4,"status":"error"}
+`,
+		},
+		{
+			name:     "consumer_error",
+			consumer: consumertest.NewErr(errors.New("bad consumer")),
+			req: func() *http.Request {
+				return httptest.NewRequest("POST", "http://localhost/services/collector?event_status=1", strings.NewReader(`{"event":"first"}{"event":"second"}`))
+			},
+			want: `{"index":0,"status":"error"}
+{"index":1,"status":"error"}
+`,
+		},
+		{
+			name:     "no_body",
+			consumer: consumertest.NewNop(),
+			req: func() *http.Request {
+				return httptest.NewRequest("POST", "http://localhost/services/collector?event_status=true", nil)
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *createDefaultConfig().(*Config), tt.consumer)
+			require.NoError(t, err)
+			r := rcv.(*splunkReceiver)
+
+			w := httptest.NewRecorder()
+			r.handleReq(w, tt.req())
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.want, w.Body.String())
+		})
+	}
+}
+
+func Test_splunkhecReceiver_eventStatusConsumedEvents(t *testing.T) {
+	body := `{"event":"first"}
+{"event":"nested fields","fields":{"nested":{"a":1}}}
+{"event":"second"}`
+
+	sink := new(consumertest.LogsSink)
+	rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *createDefaultConfig().(*Config), sink)
+	require.NoError(t, err)
+	r := rcv.(*splunkReceiver)
+
+	w := httptest.NewRecorder()
+	r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector?event_status=true", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The accepted events are consumed even though the request holds an invalid event.
+	require.Len(t, sink.AllLogs(), 1)
+	logRecords := sink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	require.Equal(t, 2, logRecords.Len())
+	assert.Equal(t, "first", logRecords.At(0).Body().Str())
+	assert.Equal(t, "second", logRecords.At(1).Body().Str())
+}
+
+func Test_splunkhecReceiver_gzipResponses(t *testing.T) {
+	longText := strings.Repeat("Request failed. ", 100)
+	config := createDefaultConfig().(*Config)
+	config.Responses = map[string]string{
+		"internal_server_error": longText,
+	}
+	currentTime := float64(time.Now().UnixNano()) / 1e6
+	msgBytes, err := json.Marshal(buildSplunkHecMsg(currentTime, 3))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		consumer       consumer.Logs
+		acceptEncoding string
+		wantGzip       bool
+		wantCode       int
+		wantBody       interface{}
+	}{
+		{
+			name:           "large_response_gzip_accepted",
+			consumer:       consumertest.NewErr(errors.New("bad consumer")),
+			acceptEncoding: "deflate, gzip;q=0.8",
+			wantGzip:       true,
+			wantCode:       http.StatusInternalServerError,
+			wantBody:       hecResponseBody(longText, responseCodeInternalServerError),
+		},
+		{
+			name:     "large_response_gzip_not_accepted",
+			consumer: consumertest.NewErr(errors.New("bad consumer")),
+			wantCode: http.StatusInternalServerError,
+			wantBody: hecResponseBody(longText, responseCodeInternalServerError),
+		},
+		{
+			name:           "large_response_gzip_refused",
+			consumer:       consumertest.NewErr(errors.New("bad consumer")),
+			acceptEncoding: "gzip;q=0",
+			wantCode:       http.StatusInternalServerError,
+			wantBody:       hecResponseBody(longText, responseCodeInternalServerError),
+		},
+		{
+			name:           "small_response",
+			consumer:       consumertest.NewNop(),
+			acceptEncoding: "gzip",
+			wantCode:       http.StatusOK,
+			wantBody:       hecResponseBody(responseSuccess, responseCodeSuccess),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, tt.consumer)
+			require.NoError(t, err)
+			r := rcv.(*splunkReceiver)
+
+			req := httptest.NewRequest("POST", "http://localhost/services/collector", bytes.NewReader(msgBytes))
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			r.handleReq(w, req)
+			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.wantCode == http.StatusOK {
+				assert.Empty(t, w.Header().Get("Vary"), "small responses are never compressed")
+			} else {
+				assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+			}
+
+			respBody := w.Body.Bytes()
+			if tt.wantGzip {
+				assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+				reader, err := gzip.NewReader(w.Body)
+				require.NoError(t, err)
+				respBody, err = io.ReadAll(reader)
+				require.NoError(t, err)
+			} else {
+				assert.Empty(t, w.Header().Get("Content-Encoding"))
+			}
+			var body interface{}
+			require.NoError(t, json.Unmarshal(respBody, &body))
+			assert.Equal(t, tt.wantBody, body)
+		})
+	}
 }
 
 func Test_splunkhecReceiver_TLS(t *testing.T) {
@@ -430,24 +1483,101 @@ func Test_splunkhecReceiver_TLS(t *testing.T) {
 			CertFile: "./testdata/client.crt",
 			KeyFile:  "./testdata/client.key",
 		},
-		ServerName: "localhost",
-	}
-	tls, errTLS := tlscs.LoadTLSConfig()
-	assert.NoError(t, errTLS)
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: tls,
+		ServerName: "localhost",
+	}
+	tls, errTLS := tlscs.LoadTLSConfig()
+	assert.NoError(t, errTLS)
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tls,
+		},
+	}
+
+	resp, err := client.Do(req)
+	require.NoErrorf(t, err, "should not have failed when sending to splunk HEC receiver %v", err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	t.Log("Splunk HEC Request Received")
+
+	got := sink.AllLogs()
+	require.Equal(t, 1, len(got))
+	assert.Equal(t, want, got[0])
+}
+
+func Test_splunkhecReceiver_HTTP2(t *testing.T) {
+	tlscs := configtls.TLSClientSetting{
+		TLSSetting: configtls.TLSSetting{
+			CAFile:   "./testdata/ca.crt",
+			CertFile: "./testdata/client.crt",
+			KeyFile:  "./testdata/client.key",
+		},
+		ServerName: "localhost",
+	}
+	clientTLS, err := tlscs.LoadTLSConfig()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		tls       bool
+		enableH2C bool
+		transport http.RoundTripper
+	}{
+		{
+			name: "tls",
+			tls:  true,
+			transport: &http.Transport{
+				TLSClientConfig:   clientTLS,
+				ForceAttemptHTTP2: true,
+			},
+		},
+		{
+			name:      "h2c",
+			enableH2C: true,
+			transport: &http2.Transport{
+				AllowHTTP: true,
+				DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+					return net.Dial(network, addr)
+				},
+			},
 		},
 	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := testutil.GetAvailableLocalAddress(t)
+			cfg := createDefaultConfig().(*Config)
+			cfg.Endpoint = addr
+			cfg.EnableH2C = tt.enableH2C
+			scheme := "http"
+			if tt.tls {
+				scheme = "https"
+				cfg.TLSSetting = &configtls.TLSServerSetting{
+					TLSSetting: configtls.TLSSetting{
+						CertFile: "./testdata/server.crt",
+						KeyFile:  "./testdata/server.key",
+					},
+				}
+			}
+			sink := new(consumertest.LogsSink)
+			r, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *cfg, sink)
+			require.NoError(t, err)
+			require.NoError(t, r.Start(context.Background(), newAssertNoErrorHost(t)))
+			defer func() {
+				require.NoError(t, r.Shutdown(context.Background()))
+			}()
 
-	resp, err := client.Do(req)
-	require.NoErrorf(t, err, "should not have failed when sending to splunk HEC receiver %v", err)
-	assert.Equal(t, http.StatusOK, resp.StatusCode)
-	t.Log("Splunk HEC Request Received")
+			body, err := json.Marshal(buildSplunkHecMsg(1, 0))
+			require.NoError(t, err)
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s://%s/services/collector", scheme, addr), bytes.NewReader(body))
+			require.NoError(t, err)
 
-	got := sink.AllLogs()
-	require.Equal(t, 1, len(got))
-	assert.Equal(t, want, got[0])
+			client := &http.Client{Transport: tt.transport}
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, 2, resp.ProtoMajor)
+			assert.Equal(t, 1, sink.LogRecordCount())
+		})
+	}
 }
 
 func Test_splunkhecReceiver_AccessTokenPassthrough(t *testing.T) {
@@ -593,6 +1723,182 @@ func Test_splunkhecReceiver_AccessTokenPassthrough(t *testing.T) {
 	}
 }
 
+func Test_splunkhecReceiver_ChannelPassthrough(t *testing.T) {
+	currentTime := float64(time.Now().UnixNano()) / 1e6
+	msgBytes, err := json.Marshal(buildSplunkHecMsg(currentTime, 3))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		passthrough bool
+		channel     string
+		path        string
+		body        []byte
+		wantChannel string
+	}{
+		{
+			name:        "event_passthrough",
+			passthrough: true,
+			channel:     "FE0ECFAD-13D5-401B-847D-77833BD77131",
+			path:        "http://localhost/services/collector",
+			body:        msgBytes,
+			wantChannel: "FE0ECFAD-13D5-401B-847D-77833BD77131",
+		},
+		{
+			name:        "raw_passthrough",
+			passthrough: true,
+			channel:     "FE0ECFAD-13D5-401B-847D-77833BD77131",
+			path:        "http://localhost/services/collector/raw",
+			body:        []byte("line\n"),
+			wantChannel: "FE0ECFAD-13D5-401B-847D-77833BD77131",
+		},
+		{
+			name:        "query_param_passthrough",
+			passthrough: true,
+			path:        "http://localhost/services/collector?channel=0B2A5F1E-6D2C-4B8B-9E43-2C4D7A1F3E55",
+			body:        msgBytes,
+			wantChannel: "0B2A5F1E-6D2C-4B8B-9E43-2C4D7A1F3E55",
+		},
+		{
+			name:        "raw_query_param_passthrough",
+			passthrough: true,
+			path:        "http://localhost/services/collector/raw?channel=0B2A5F1E-6D2C-4B8B-9E43-2C4D7A1F3E55",
+			body:        []byte("line\n"),
+			wantChannel: "0B2A5F1E-6D2C-4B8B-9E43-2C4D7A1F3E55",
+		},
+		{
+			name:        "header_takes_precedence_over_query_param",
+			passthrough: true,
+			channel:     "FE0ECFAD-13D5-401B-847D-77833BD77131",
+			path:        "http://localhost/services/collector?channel=0B2A5F1E-6D2C-4B8B-9E43-2C4D7A1F3E55",
+			body:        msgBytes,
+			wantChannel: "FE0ECFAD-13D5-401B-847D-77833BD77131",
+		},
+		{
+			name:        "passthrough_without_header",
+			passthrough: true,
+			path:        "http://localhost/services/collector",
+			body:        msgBytes,
+		},
+		{
+			name:    "passthrough_disabled",
+			channel: "FE0ECFAD-13D5-401B-847D-77833BD77131",
+			path:    "http://localhost/services/collector",
+			body:    msgBytes,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createDefaultConfig().(*Config)
+			config.ChannelPassthrough = tt.passthrough
+			sink := new(consumertest.LogsSink)
+			rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, sink)
+			require.NoError(t, err)
+			r := rcv.(*splunkReceiver)
+
+			req := httptest.NewRequest("POST", tt.path, bytes.NewReader(tt.body))
+			if tt.channel != "" {
+				req.Header.Set("X-Splunk-Request-Channel", tt.channel)
+			}
+			w := httptest.NewRecorder()
+			if strings.HasPrefix(req.URL.Path, "/services/collector/raw") {
+				r.handleRawReq(w, req)
+			} else {
+				r.handleReq(w, req)
+			}
+
+			assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+			require.Len(t, sink.AllLogs(), 1)
+			channel, ok := sink.AllLogs()[0].ResourceLogs().At(0).Resource().Attributes().Get("com.splunk.hec.channel")
+			if tt.wantChannel == "" {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.wantChannel, channel.Str())
+		})
+	}
+}
+
+func Test_splunkhecReceiver_tokens(t *testing.T) {
+	msgBytes, err := json.Marshal(buildSplunkHecMsg(float64(time.Now().UnixNano())/1e6, 3))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		token     string
+		path      string
+		body      []byte
+		wantAttrs map[string]interface{}
+	}{
+		{
+			name:      "event_known_token",
+			token:     "acme-token",
+			path:      "http://localhost/services/collector",
+			body:      msgBytes,
+			wantAttrs: map[string]interface{}{"tenant": "acme", "tier": "gold"},
+		},
+		{
+			name:      "raw_known_token",
+			token:     "acme-token",
+			path:      "http://localhost/services/collector/raw",
+			body:      []byte("line\n"),
+			wantAttrs: map[string]interface{}{"tenant": "acme", "tier": "gold"},
+		},
+		{
+			name:  "unknown_token",
+			token: "unknown-token",
+			path:  "http://localhost/services/collector",
+			body:  msgBytes,
+		},
+		{
+			name: "no_token",
+			path: "http://localhost/services/collector",
+			body: msgBytes,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createDefaultConfig().(*Config)
+			config.Tokens = map[string]map[string]string{
+				"acme-token":   {"tenant": "acme", "tier": "gold"},
+				"globex-token": {"tenant": "globex"},
+			}
+			sink := new(consumertest.LogsSink)
+			rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, sink)
+			require.NoError(t, err)
+			r := rcv.(*splunkReceiver)
+
+			req := httptest.NewRequest("POST", tt.path, bytes.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", splunk.HECTokenHeader+" "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			if strings.HasPrefix(req.URL.Path, "/services/collector/raw") {
+				r.handleRawReq(w, req)
+			} else {
+				r.handleReq(w, req)
+			}
+
+			assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+			require.Len(t, sink.AllLogs(), 1)
+			attrs := sink.AllLogs()[0].ResourceLogs().At(0).Resource().Attributes()
+			for _, key := range []string{"tenant", "tier"} {
+				value, ok := attrs.Get(key)
+				if tt.wantAttrs[key] == nil {
+					assert.False(t, ok, key)
+					continue
+				}
+				require.True(t, ok, key)
+				assert.Equal(t, tt.wantAttrs[key], value.Str())
+			}
+			// The token itself is not kept unless access_token_passthrough is enabled.
+			_, ok := attrs.Get(splunk.HecTokenLabel)
+			assert.False(t, ok)
+		})
+	}
+}
+
 func Test_Logs_splunkhecReceiver_IndexSourceTypePassthrough(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -672,10 +1978,8 @@ func Test_Logs_splunkhecReceiver_IndexSourceTypePassthrough(t *testing.T) {
 			resp := w.Result()
 			respBytes, err := io.ReadAll(resp.Body)
 			assert.NoError(t, err)
-			var bodyStr string
-			assert.NoError(t, json.Unmarshal(respBytes, &bodyStr))
 			assert.Equal(t, http.StatusOK, resp.StatusCode)
-			assert.Equal(t, responseOK, bodyStr)
+			assert.Equal(t, `{"text":"Success","code":0}`, string(respBytes))
 			select {
 			case <-done:
 				break
@@ -766,10 +2070,8 @@ func Test_Metrics_splunkhecReceiver_IndexSourceTypePassthrough(t *testing.T) {
 			resp := w.Result()
 			respBytes, err := io.ReadAll(resp.Body)
 			assert.NoError(t, err)
-			var bodyStr string
-			assert.NoError(t, json.Unmarshal(respBytes, &bodyStr))
 			assert.Equal(t, http.StatusOK, resp.StatusCode)
-			assert.Equal(t, responseOK, bodyStr)
+			assert.Equal(t, `{"text":"Success","code":0}`, string(respBytes))
 			select {
 			case <-done:
 				break
@@ -780,6 +2082,148 @@ func Test_Metrics_splunkhecReceiver_IndexSourceTypePassthrough(t *testing.T) {
 	}
 }
 
+func Test_splunkhecReceiver_ShutdownDrainsInFlightRequests(t *testing.T) {
+	addr := testutil.GetAvailableLocalAddress(t)
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = addr
+
+	consuming := make(chan struct{})
+	slowConsumer, err := consumer.NewLogs(func(context.Context, plog.Logs) error {
+		close(consuming)
+		time.Sleep(500 * time.Millisecond)
+		return nil
+	})
+	require.NoError(t, err)
+	r, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *cfg, slowConsumer)
+	require.NoError(t, err)
+	require.NoError(t, r.Start(context.Background(), componenttest.NewNopHost()))
+
+	msgBytes, err := json.Marshal(buildSplunkHecMsg(float64(time.Now().UnixNano())/1e6, 3))
+	require.NoError(t, err)
+	respCh := make(chan *http.Response)
+	go func() {
+		resp, errPost := http.Post(fmt.Sprintf("http://%s", addr), "application/json", bytes.NewReader(msgBytes))
+		assert.NoError(t, errPost)
+		respCh <- resp
+	}()
+
+	<-consuming
+	require.NoError(t, r.Shutdown(context.Background()))
+
+	resp := <-respCh
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, resp.Body.Close())
+}
+
+func Test_splunkhecReceiver_debugStats(t *testing.T) {
+	addr := testutil.GetAvailableLocalAddress(t)
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = addr
+	cfg.EnableDebugStats = true
+
+	failing := true
+	nextConsumer, err := consumer.NewLogs(func(context.Context, plog.Logs) error {
+		if failing {
+			return errors.New("bad consumer")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	r, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *cfg, nextConsumer)
+	require.NoError(t, err)
+	require.NoError(t, r.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		require.NoError(t, r.Shutdown(context.Background()))
+	}()
+
+	post := func(path string, body string) {
+		resp, errPost := http.Post(fmt.Sprintf("http://%s%s", addr, path), "application/json", strings.NewReader(body))
+		require.NoError(t, errPost)
+		require.NoError(t, resp.Body.Close())
+	}
+	post("/services/collector", `{"event":"first"}{"event":"second"}`)
+	failing = false
+	post("/services/collector", `{"event":"third"}`)
+	post("/services/collector", `{"event":`)
+	post("/services/collector/raw", "line 1\nline 2\n")
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/debug/hec-stats", addr))
+	require.NoError(t, err)
+	respBytes, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"requests_total":4,"events_total":5,"decode_errors_total":1,"consumer_errors_total":1}`, string(respBytes))
+
+	// The stats are read-only.
+	resp, err = http.Post(fmt.Sprintf("http://%s/debug/hec-stats", addr), "application/json", nil)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func Test_splunkhecReceiver_debugStatsDisabled(t *testing.T) {
+	addr := testutil.GetAvailableLocalAddress(t)
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = addr
+	r, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, r.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		require.NoError(t, r.Shutdown(context.Background()))
+	}()
+
+	// The path is handled as any other HEC request, which must use the POST method.
+	resp, err := http.Get(fmt.Sprintf("http://%s/debug/hec-stats", addr))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func Test_splunkhecReceiver_traceContext(t *testing.T) {
+	addr := testutil.GetAvailableLocalAddress(t)
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = addr
+
+	spanContexts := make(chan trace.SpanContext, 2)
+	nextConsumer, err := consumer.NewLogs(func(ctx context.Context, _ plog.Logs) error {
+		spanContexts <- trace.SpanContextFromContext(ctx)
+		return nil
+	})
+	require.NoError(t, err)
+	r, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *cfg, nextConsumer)
+	require.NoError(t, err)
+	require.NoError(t, r.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		require.NoError(t, r.Shutdown(context.Background()))
+	}()
+
+	msgBytes, err := json.Marshal(buildSplunkHecMsg(float64(time.Now().UnixNano())/1e6, 3))
+	require.NoError(t, err)
+	post := func(traceparent string) {
+		req, errReq := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s", addr), bytes.NewReader(msgBytes))
+		require.NoError(t, errReq)
+		if traceparent != "" {
+			req.Header.Set("traceparent", traceparent)
+		}
+		resp, errPost := http.DefaultClient.Do(req)
+		require.NoError(t, errPost)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	post("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	sc := <-spanContexts
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", sc.TraceID().String())
+	assert.True(t, sc.IsSampled())
+
+	post("")
+	sc = <-spanContexts
+	assert.False(t, sc.TraceID().IsValid(), "no remote parent without trace context headers")
+}
+
 func buildSplunkHecMetricsMsg(time float64, value int64, dimensions uint) *splunk.Event {
 	ev := &splunk.Event{
 		Time:  &time,
@@ -810,6 +2254,13 @@ func buildSplunkHecMsg(time float64, dimensions uint) *splunk.Event {
 	return ev
 }
 
+func hecResponseBody(text string, code int) map[string]interface{} {
+	return map[string]interface{}{
+		"text": text,
+		"code": float64(code),
+	}
+}
+
 type badReqBody struct{}
 
 var _ io.ReadCloser = (*badReqBody)(nil)
@@ -851,14 +2302,14 @@ func Test_splunkhecReceiver_handleRawReq(t *testing.T) {
 	tests := []struct {
 		name           string
 		req            *http.Request
-		assertResponse func(t *testing.T, status int, body string)
+		assertResponse func(t *testing.T, status int, body interface{})
 	}{
 		{
 			name: "incorrect_method",
 			req:  httptest.NewRequest("PUT", "http://localhost/foo", nil),
-			assertResponse: func(t *testing.T, status int, body string) {
+			assertResponse: func(t *testing.T, status int, body interface{}) {
 				assert.Equal(t, http.StatusBadRequest, status)
-				assert.Equal(t, responseInvalidMethod, body)
+				assert.Equal(t, hecResponseBody(responseInvalidMethod, responseCodeInvalidDataFormat), body)
 			},
 		},
 		{
@@ -868,7 +2319,7 @@ func Test_splunkhecReceiver_handleRawReq(t *testing.T) {
 				req.Header.Set("Content-Type", "application/not-json")
 				return req
 			}(),
-			assertResponse: func(t *testing.T, status int, body string) {
+			assertResponse: func(t *testing.T, status int, body interface{}) {
 				assert.Equal(t, http.StatusOK, status)
 			},
 		},
@@ -879,9 +2330,9 @@ func Test_splunkhecReceiver_handleRawReq(t *testing.T) {
 				req.Header.Set("Content-Encoding", "superzipper")
 				return req
 			}(),
-			assertResponse: func(t *testing.T, status int, body string) {
+			assertResponse: func(t *testing.T, status int, body interface{}) {
 				assert.Equal(t, http.StatusUnsupportedMediaType, status)
-				assert.Equal(t, responseInvalidEncoding, body)
+				assert.Equal(t, hecResponseBody(responseInvalidEncoding, responseCodeInvalidDataFormat), body)
 			},
 		},
 		{
@@ -890,7 +2341,7 @@ func Test_splunkhecReceiver_handleRawReq(t *testing.T) {
 				req := httptest.NewRequest("POST", "http://localhost/foo", bytes.NewReader(nil))
 				return req
 			}(),
-			assertResponse: func(t *testing.T, status int, body string) {
+			assertResponse: func(t *testing.T, status int, body interface{}) {
 				assert.Equal(t, http.StatusOK, status)
 			},
 		},
@@ -901,7 +2352,7 @@ func Test_splunkhecReceiver_handleRawReq(t *testing.T) {
 				req := httptest.NewRequest("POST", "http://localhost/foo", strings.NewReader("foo\nbar"))
 				return req
 			}(),
-			assertResponse: func(t *testing.T, status int, body string) {
+			assertResponse: func(t *testing.T, status int, body interface{}) {
 				assert.Equal(t, http.StatusOK, status)
 			},
 		},
@@ -913,7 +2364,7 @@ func Test_splunkhecReceiver_handleRawReq(t *testing.T) {
 				req := httptest.NewRequest("POST", "http://localhost/foo", bytes.NewReader(msgBytes))
 				return req
 			}(),
-			assertResponse: func(t *testing.T, status int, body string) {
+			assertResponse: func(t *testing.T, status int, body interface{}) {
 				assert.Equal(t, http.StatusOK, status)
 			},
 		},
@@ -933,7 +2384,7 @@ func Test_splunkhecReceiver_handleRawReq(t *testing.T) {
 				req.Header.Set("Content-Encoding", "gzip")
 				return req
 			}(),
-			assertResponse: func(t *testing.T, status int, body string) {
+			assertResponse: func(t *testing.T, status int, body interface{}) {
 				assert.Equal(t, http.StatusOK, status)
 			},
 		},
@@ -947,9 +2398,9 @@ func Test_splunkhecReceiver_handleRawReq(t *testing.T) {
 				req.Header.Set("Content-Encoding", "gzip")
 				return req
 			}(),
-			assertResponse: func(t *testing.T, status int, body string) {
+			assertResponse: func(t *testing.T, status int, body interface{}) {
 				assert.Equal(t, http.StatusBadRequest, status)
-				assert.Equal(t, responseErrGzipReader, body)
+				assert.Equal(t, hecResponseBody(responseErrGzipReader, responseCodeInvalidDataFormat), body)
 			},
 		},
 	}
@@ -972,18 +2423,109 @@ func Test_splunkhecReceiver_handleRawReq(t *testing.T) {
 			respBytes, err := io.ReadAll(resp.Body)
 			assert.NoError(t, err)
 
-			var bodyStr string
+			var body interface{}
 			if len(respBytes) > 0 {
-				assert.NoError(t, json.Unmarshal(respBytes, &bodyStr))
-			} else {
-				bodyStr = ""
+				assert.NoError(t, json.Unmarshal(respBytes, &body))
+			}
+
+			tt.assertResponse(t, resp.StatusCode, body)
+		})
+	}
+}
+
+func Test_splunkhecReceiver_rawMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		defaults bool
+		want     map[string]interface{}
+	}{
+		{
+			name: "unset",
+			want: map[string]interface{}{},
+		},
+		{
+			name:  "query_params",
+			query: "?host=qhost&source=qsource&sourcetype=qsourcetype&index=qindex",
+			want: map[string]interface{}{
+				"host.name":             "qhost",
+				"com.splunk.source":     "qsource",
+				"com.splunk.sourcetype": "qsourcetype",
+				"com.splunk.index":      "qindex",
+			},
+		},
+		{
+			name:     "config_defaults",
+			defaults: true,
+			want: map[string]interface{}{
+				"host.name":             "dhost",
+				"com.splunk.source":     "dsource",
+				"com.splunk.sourcetype": "dsourcetype",
+				"com.splunk.index":      "dindex",
+			},
+		},
+		{
+			name:     "query_params_over_config_defaults",
+			query:    "?source=qsource&index=qindex",
+			defaults: true,
+			want: map[string]interface{}{
+				"host.name":             "dhost",
+				"com.splunk.source":     "qsource",
+				"com.splunk.sourcetype": "dsourcetype",
+				"com.splunk.index":      "qindex",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createDefaultConfig().(*Config)
+			if tt.defaults {
+				config.DefaultHost = "dhost"
+				config.DefaultSource = "dsource"
+				config.DefaultSourcetype = "dsourcetype"
+				config.DefaultIndex = "dindex"
 			}
+			sink := new(consumertest.LogsSink)
+			rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, sink)
+			require.NoError(t, err)
 
-			tt.assertResponse(t, resp.StatusCode, bodyStr)
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "http://localhost/services/collector/raw"+tt.query, strings.NewReader("first line\nsecond line\n"))
+			rcv.(*splunkReceiver).handleRawReq(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			require.Len(t, sink.AllLogs(), 1)
+			logs := sink.AllLogs()[0]
+			assert.Equal(t, 2, logs.LogRecordCount())
+			require.Equal(t, 1, logs.ResourceLogs().Len())
+			assert.Equal(t, tt.want, logs.ResourceLogs().At(0).Resource().Attributes().AsRaw())
 		})
 	}
 }
 
+func Test_splunkhecReceiver_rawMetadataSplitByIndex(t *testing.T) {
+	config := createDefaultConfig().(*Config)
+	config.SplitByIndex = true
+	config.DefaultSourcetype = "dsourcetype"
+	config.DefaultIndex = "dindex"
+	sink := new(consumertest.LogsSink)
+	rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, sink)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "http://localhost/services/collector/raw?host=qhost", strings.NewReader("line\n"))
+	rcv.(*splunkReceiver).handleRawReq(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	require.Len(t, sink.AllLogs(), 1)
+	rl := sink.AllLogs()[0].ResourceLogs().At(0)
+	assert.Equal(t, map[string]interface{}{"com.splunk.index": "dindex"}, rl.Resource().Attributes().AsRaw())
+	assert.Equal(t, map[string]interface{}{
+		"host.name":             "qhost",
+		"com.splunk.sourcetype": "dsourcetype",
+	}, rl.ScopeLogs().At(0).LogRecords().At(0).Attributes().AsRaw())
+}
+
 func Test_splunkhecreceiver_handleHealthPath(t *testing.T) {
 	config := createDefaultConfig().(*Config)
 	sink := new(consumertest.LogsSink)
@@ -1069,7 +2611,7 @@ func Test_splunkhecreceiver_handle_nested_fields(t *testing.T) {
 				assert.Equal(t, 1, sink.LogRecordCount())
 			} else {
 				assert.Equal(t, http.StatusBadRequest, w.Code)
-				assert.Equal(t, fmt.Sprintf(responseErrHandlingIndexedFields, 0), w.Body.String())
+				assert.Equal(t, `{"text":"Error in handling indexed fields","code":15,"invalid-event-number":0}`, w.Body.String())
 			}
 
 		})
@@ -1106,3 +2648,126 @@ func BenchmarkHandleReq(b *testing.B) {
 		assert.NoError(b, err)
 	}
 }
+
+func Test_splunkhecReceiver_ParallelDecode(t *testing.T) {
+	var body bytes.Buffer
+	for i := 0; i < 100; i++ {
+		msg := buildSplunkHecMsg(float64(i), 3)
+		msg.Event = fmt.Sprintf("event %d", i)
+		msgBytes, err := json.Marshal(msg)
+		require.NoError(t, err)
+		body.Write(msgBytes)
+	}
+
+	consume := func(t *testing.T, parallelDecode ParallelDecodeConfig) plog.Logs {
+		config := createDefaultConfig().(*Config)
+		config.ParallelDecode = parallelDecode
+		sink := new(consumertest.LogsSink)
+		rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, sink)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		rcv.(*splunkReceiver).handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", bytes.NewReader(body.Bytes())))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, sink.AllLogs(), 1)
+		return sink.AllLogs()[0]
+	}
+
+	sequential := consume(t, ParallelDecodeConfig{})
+	parallel := consume(t, ParallelDecodeConfig{Enabled: true, Workers: 4})
+	assert.Equal(t, 100, parallel.LogRecordCount())
+	assert.Equal(t, sequential, parallel)
+	logRecords := parallel.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	for i := 0; i < logRecords.Len(); i++ {
+		assert.Equal(t, fmt.Sprintf("event %d", i), logRecords.At(i).Body().Str())
+	}
+
+	// Requests smaller than the minimum content length are decoded sequentially.
+	smaller := consume(t, ParallelDecodeConfig{Enabled: true, Workers: 4, MinContentLength: int64(body.Len() + 1)})
+	assert.Equal(t, sequential, smaller)
+}
+
+func Test_splunkhecReceiver_ParallelDecodeGzip(t *testing.T) {
+	// Through the server started by Start, gzip requests reach the handler without Content-Length.
+	var body bytes.Buffer
+	for i := 0; i < 100; i++ {
+		msgBytes, err := json.Marshal(buildSplunkHecMsg(float64(i), 3))
+		require.NoError(t, err)
+		body.Write(msgBytes)
+	}
+	gzipped := gzipBytes(t, body.Bytes())
+
+	tests := []struct {
+		name       string
+		minSize    int64
+		concurrent bool
+	}{
+		{name: "above_min_size", minSize: int64(body.Len() / 2), concurrent: true},
+		{name: "below_min_size", minSize: int64(body.Len() + 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := testutil.GetAvailableLocalAddress(t)
+			cfg := createDefaultConfig().(*Config)
+			cfg.Endpoint = addr
+			cfg.ParallelDecode = ParallelDecodeConfig{Enabled: true, Workers: 4, MinContentLength: tt.minSize}
+			core, logs := observer.New(zapcore.DebugLevel)
+			settings := receivertest.NewNopCreateSettings()
+			settings.Logger = zap.New(core)
+			sink := new(consumertest.LogsSink)
+			r, err := newLogsReceiver(settings, *cfg, sink)
+			require.NoError(t, err)
+			require.NoError(t, r.Start(context.Background(), componenttest.NewNopHost()))
+			defer func() {
+				require.NoError(t, r.Shutdown(context.Background()))
+			}()
+
+			req, err := http.NewRequest("POST", fmt.Sprintf("http://%s/services/collector", addr), bytes.NewReader(gzipped))
+			require.NoError(t, err)
+			req.Header.Set("Content-Encoding", "gzip")
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, 100, sink.LogRecordCount())
+			concurrent := logs.FilterMessage("Decoded the events of the request concurrently").Len()
+			if tt.concurrent {
+				assert.Equal(t, 1, concurrent)
+			} else {
+				assert.Equal(t, 0, concurrent)
+			}
+		})
+	}
+}
+
+// BenchmarkHandleReqParallelDecode measures the throughput of large requests depending
+// on the number of workers decoding their events. The gain is bounded by GOMAXPROCS.
+func BenchmarkHandleReqParallelDecode(b *testing.B) {
+	var body bytes.Buffer
+	for i := 0; i < 10000; i++ {
+		msgBytes, err := json.Marshal(buildSplunkHecMsg(float64(i), 10))
+		require.NoError(b, err)
+		body.Write(msgBytes)
+	}
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers_%d", workers), func(b *testing.B) {
+			config := createDefaultConfig().(*Config)
+			config.ParallelDecode = ParallelDecodeConfig{Enabled: workers > 1, Workers: workers}
+			rcv, err := newLogsReceiver(receivertest.NewNopCreateSettings(), *config, consumertest.NewNop())
+			require.NoError(b, err)
+			r := rcv.(*splunkReceiver)
+
+			b.SetBytes(int64(body.Len()))
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				w := httptest.NewRecorder()
+				r.handleReq(w, httptest.NewRequest("POST", "http://localhost/services/collector", bytes.NewReader(body.Bytes())))
+				if w.Code != http.StatusOK {
+					b.Fatalf("unexpected status code %d", w.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2020, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhecreceiver // import "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/splunkhecreceiver"
+
+import (
+	jsoniter "github.com/json-iterator/go"
+)
+
+// hecResponses holds the bodies of the responses to requests, with their
+// texts optionally overridden by Config.Responses.
+type hecResponses struct {
+	success                []byte
+	invalidMethod          []byte
+	invalidEncoding        []byte
+	gzipReader             []byte
+	unmarshalBody          []byte
+	internalServerError    []byte
+	unsupportedMetricEvent []byte
+	unsupportedLogEvent    []byte
+	invalidTimeQueryParam  []byte
+	serverBusy             []byte
+	indexedFieldsText      string
+}
+
+// indexedFieldsResponse is the body of the response to an event with invalid indexed fields.
+type indexedFieldsResponse struct {
+	Text               string `json:"text"`
+	Code               int    `json:"code"`
+	InvalidEventNumber int    `json:"invalid-event-number"`
+}
+
+func newHECResponses(overrides map[string]string) hecResponses {
+	text := func(key string, defaultText string) string {
+		if overrideText, ok := overrides[key]; ok {
+			return overrideText
+		}
+		return defaultText
+	}
+	return hecResponses{
+		success:                initHECResponse(text(responseKeySuccess, responseSuccess), responseCodeSuccess),
+		invalidMethod:          initHECResponse(text(responseKeyInvalidMethod, responseInvalidMethod), responseCodeInvalidDataFormat),
+		invalidEncoding:        initHECResponse(text(responseKeyInvalidEncoding, responseInvalidEncoding), responseCodeInvalidDataFormat),
+		gzipReader:             initHECResponse(text(responseKeyGzipReader, responseErrGzipReader), responseCodeInvalidDataFormat),
+		unmarshalBody:          initHECResponse(text(responseKeyUnmarshalBody, responseErrUnmarshalBody), responseCodeInvalidDataFormat),
+		internalServerError:    initHECResponse(text(responseKeyInternalServerError, responseErrInternalServerError), responseCodeInternalServerError),
+		unsupportedMetricEvent: initHECResponse(text(responseKeyUnsupportedMetricEvent, responseErrUnsupportedMetricEvent), responseCodeInvalidDataFormat),
+		unsupportedLogEvent:    initHECResponse(text(responseKeyUnsupportedLogEvent, responseErrUnsupportedLogEvent), responseCodeInvalidDataFormat),
+		invalidTimeQueryParam:  initHECResponse(text(responseKeyInvalidTimeQueryParam, responseInvalidTimeQueryParam), responseCodeInvalidDataFormat),
+		serverBusy:             initHECResponse(text(responseKeyServerBusy, responseErrServerBusy), responseCodeServerBusy),
+		indexedFieldsText:      text(responseKeyIndexedFields, responseErrHandlingIndexedFields),
+	}
+}
+
+// indexedFields returns the body of the response to the event at eventNumber
+// having invalid indexed fields.
+func (h hecResponses) indexedFields(eventNumber int) []byte {
+	respBody, err := jsoniter.Marshal(indexedFieldsResponse{
+		Text:               h.indexedFieldsText,
+		Code:               responseCodeErrorInIndexedFields,
+		InvalidEventNumber: eventNumber,
+	})
+	if err != nil {
+		// Marshaling strings and integers does not fail.
+		panic(err)
+	}
+	return respBody
+}
@@ -16,8 +16,13 @@ package splunkhecreceiver // import "github.com/open-telemetry/opentelemetry-col
 
 import (
 	"errors"
+	"fmt"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	jsoniter "github.com/json-iterator/go"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.uber.org/zap"
@@ -25,34 +30,58 @@ import (
 	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/splunk"
 )
 
+const (
+	eventField        = "event"
+	eventFieldPrefix  = "event."
+	fieldsFieldPrefix = "fields."
+	// Splunk reserved keys honored in raw log lines.
+	rawBodyKey = "_raw"
+	rawTimeKey = "__time"
+)
+
 var (
 	errCannotConvertValue = errors.New("cannot convert field value to attribute")
+
+	// severityNumbers maps upper-cased Splunk log levels to OpenTelemetry severity numbers.
+	severityNumbers = map[string]plog.SeverityNumber{
+		"TRACE":         plog.SeverityNumberTrace,
+		"DEBUG":         plog.SeverityNumberDebug,
+		"INFO":          plog.SeverityNumberInfo,
+		"INFORMATION":   plog.SeverityNumberInfo,
+		"INFORMATIONAL": plog.SeverityNumberInfo,
+		"NOTICE":        plog.SeverityNumberInfo2,
+		"WARN":          plog.SeverityNumberWarn,
+		"WARNING":       plog.SeverityNumberWarn,
+		"ERR":           plog.SeverityNumberError,
+		"ERROR":         plog.SeverityNumberError,
+		"CRIT":          plog.SeverityNumberFatal,
+		"CRITICAL":      plog.SeverityNumberFatal,
+		"FATAL":         plog.SeverityNumberFatal,
+		"ALERT":         plog.SeverityNumberFatal2,
+		"EMERG":         plog.SeverityNumberFatal3,
+		"EMERGENCY":     plog.SeverityNumberFatal3,
+	}
 )
 
-// splunkHecToLogData transforms splunk events into logs
-func splunkHecToLogData(logger *zap.Logger, events []*splunk.Event, resourceCustomizer func(pcommon.Resource), config *Config) (plog.Logs, error) {
+// splunkHecToLogData transforms splunk events into logs.
+// rawEvents holds the JSON of each event as sent by the client when KeepRawEvent is enabled.
+func splunkHecToLogData(logger *zap.Logger, events []*splunk.Event, rawEvents [][]byte, resourceCustomizer func(pcommon.Resource), config *Config) (plog.Logs, error) {
 	ld := plog.NewLogs()
 	scopeLogsMap := make(map[[4]string]plog.ScopeLogs)
-	for _, event := range events {
+	for i, event := range events {
 		key := [4]string{event.Host, event.Source, event.SourceType, event.Index}
+		if config.SplitByIndex {
+			key = [4]string{"", "", "", event.Index}
+		} else if config.HostAsLogAttribute {
+			key[0] = ""
+		}
 		var sl plog.ScopeLogs
 		var found bool
 		if sl, found = scopeLogsMap[key]; !found {
 			rl := ld.ResourceLogs().AppendEmpty()
 			sl = rl.ScopeLogs().AppendEmpty()
 			scopeLogsMap[key] = sl
-			if event.Host != "" {
-				rl.Resource().Attributes().PutStr(config.HecToOtelAttrs.Host, event.Host)
-			}
-			if event.Source != "" {
-				rl.Resource().Attributes().PutStr(config.HecToOtelAttrs.Source, event.Source)
-			}
-			if event.SourceType != "" {
-				rl.Resource().Attributes().PutStr(config.HecToOtelAttrs.SourceType, event.SourceType)
-			}
-			if event.Index != "" {
-				rl.Resource().Attributes().PutStr(config.HecToOtelAttrs.Index, event.Index)
-			}
+			putResourceMetadata(rl.Resource(), event, config)
 			if resourceCustomizer != nil {
 				resourceCustomizer(rl.Resource())
 			}
@@ -60,7 +89,18 @@ func splunkHecToLogData(logger *zap.Logger, events []*splunk.Event, resourceCust
 
 		// The SourceType field is the most logical "name" of the event.
 		logRecord := sl.LogRecords().AppendEmpty()
-		if err := convertToValue(logger, event.Event, logRecord.Body()); err != nil {
+		bodyField, timeField, severityField := eventFields(event, config)
+		body, found := selectField(event, bodyField)
+		if !found {
+			logger.Debug("Body field not found in event, using the whole event as body",
+				zap.String("body_field", bodyField))
+			raw, err := jsoniter.MarshalToString(event)
+			if err != nil {
+				return ld, err
+			}
+			body = raw
+		}
+		if err := convertToValue(logger, body, logRecord.Body()); err != nil {
 			return ld, err
 		}
 
@@ -69,6 +109,36 @@ func splunkHecToLogData(logger *zap.Logger, events []*splunk.Event, resourceCust
 		if event.Time != nil {
 			logRecord.SetTimestamp(pcommon.Timestamp(*event.Time * 1e9))
 		}
+		if timeField != "" {
+			if value, ok := selectField(event, timeField); ok {
+				if ts, ok := parseEpochSeconds(value); ok {
+					logRecord.SetTimestamp(ts)
+				}
+			}
+		}
+		if config.TimeNanosField != "" {
+			if value, ok := selectField(event, config.TimeNanosField); ok {
+				if ts, ok := parseEpochNanos(value); ok {
+					logRecord.SetTimestamp(ts)
+				}
+			}
+		}
+
+		if severityField != "" {
+			if level, ok := selectField(event, severityField); ok && level != nil {
+				severityText := fmt.Sprintf("%v", level)
+				logRecord.SetSeverityText(severityText)
+				logRecord.SetSeverityNumber(severityNumbers[strings.ToUpper(severityText)])
+			}
+		}
+
+		if config.FlattenBody {
+			if eventMap, ok := event.Event.(map[string]interface{}); ok {
+				if err := flattenInto(logger, logRecord.Attributes(), "", eventMap, 1, config.FlattenMaxDepth); err != nil {
+					return ld, err
+				}
+			}
+		}
 
 		// Set event fields first, so the specialized attributes overwrite them if needed.
 		keys := make([]string, 0, len(event.Fields))
@@ -83,11 +153,168 @@ func splunkHecToLogData(logger *zap.Logger, events []*splunk.Event, resourceCust
 				return ld, err
 			}
 		}
+		putLogRecordMetadata(logRecord, event, config)
+		if config.KeepRawEvent && i < len(rawEvents) {
+			logRecord.Attributes().PutStr(config.RawEventAttribute, string(rawEvents[i]))
+		}
 	}
 
 	return ld, nil
 }
 
+// putResourceMetadata sets the metadata of the event that is not set on its log record
+// on the resource: the index, and the host, source and sourcetype unless SplitByIndex is set.
+func putResourceMetadata(resource pcommon.Resource, event *splunk.Event, config *Config) {
+	if !config.SplitByIndex {
+		putMetadataAttributes(resource.Attributes(), event, config, !config.HostAsLogAttribute)
+	}
+	if event.Index != "" {
+		resource.Attributes().PutStr(config.HecToOtelAttrs.Index, event.Index)
+	}
+}
+
+// putLogRecordMetadata sets the metadata of the event that is not set on its resource
+// on the log record, see putResourceMetadata.
+func putLogRecordMetadata(logRecord plog.LogRecord, event *splunk.Event, config *Config) {
+	if config.SplitByIndex {
+		putMetadataAttributes(logRecord.Attributes(), event, config, true)
+	} else if config.HostAsLogAttribute && event.Host != "" {
+		logRecord.Attributes().PutStr(config.HecToOtelAttrs.Host, event.Host)
+	}
+}
+
+// putMetadataAttributes sets the source and sourcetype of the event on attrs, and its host
+// when includeHost is set.
+func putMetadataAttributes(attrs pcommon.Map, event *splunk.Event, config *Config, includeHost bool) {
+	if includeHost && event.Host != "" {
+		attrs.PutStr(config.HecToOtelAttrs.Host, event.Host)
+	}
+	if event.Source != "" {
+		attrs.PutStr(config.HecToOtelAttrs.Source, event.Source)
+	}
+	if event.SourceType != "" {
+		attrs.PutStr(config.HecToOtelAttrs.SourceType, event.SourceType)
+	}
+}
+
+// selectField returns the value of the event designated by path and
+// whether it was found. An empty path selects the "event" field.
+func selectField(event *splunk.Event, path string) (interface{}, bool) {
+	switch {
+	case path == "" || path == eventField:
+		return event.Event, true
+	case strings.HasPrefix(path, fieldsFieldPrefix):
+		val, ok := event.Fields[path[len(fieldsFieldPrefix):]]
+		return val, ok
+	case strings.HasPrefix(path, eventFieldPrefix):
+		current := event.Event
+		for _, key := range strings.Split(path[len(eventFieldPrefix):], ".") {
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if current, ok = m[key]; !ok {
+				return nil, false
+			}
+		}
+		return current, true
+	}
+	return nil, false
+}
+
+// hasEmptyBody returns whether the body selected for the event is null, or a string that
+// is empty or only holds whitespace. Events without the body field are not empty, as the
+// whole event is then used as body.
+func hasEmptyBody(event *splunk.Event, config *Config) bool {
+	bodyField, _, _ := eventFields(event, config)
+	body, found := selectField(event, bodyField)
+	if !found {
+		return false
+	}
+	switch b := body.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(b) == ""
+	}
+	return false
+}
+
+// setExactInteger replaces the value of the event designated by path with the int64 held
+// by the raw event when it is a JSON integer, which float64 values cannot hold exactly
+// beyond 2^53, e.g. nanoseconds since the epoch. eventKey is the key of the raw event
+// holding the "event" field.
+func setExactInteger(event *splunk.Event, rawEvent []byte, path string, eventKey string) {
+	if eventKey == "" {
+		eventKey = eventField
+	}
+	var rawPath []interface{}
+	switch {
+	case path == "" || path == eventField:
+		rawPath = []interface{}{eventKey}
+	case strings.HasPrefix(path, fieldsFieldPrefix):
+		rawPath = []interface{}{"fields", path[len(fieldsFieldPrefix):]}
+	case strings.HasPrefix(path, eventFieldPrefix):
+		rawPath = []interface{}{eventKey}
+		for _, key := range strings.Split(path[len(eventFieldPrefix):], ".") {
+			rawPath = append(rawPath, key)
+		}
+	}
+	value := jsoniter.Get(rawEvent, rawPath...)
+	if value.ValueType() != jsoniter.NumberValue {
+		return
+	}
+	n, err := strconv.ParseInt(value.ToString(), 10, 64)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case path == "" || path == eventField:
+		event.Event = n
+	case strings.HasPrefix(path, fieldsFieldPrefix):
+		event.Fields[path[len(fieldsFieldPrefix):]] = n
+	default:
+		keys := strings.Split(path[len(eventFieldPrefix):], ".")
+		m, ok := event.Event.(map[string]interface{})
+		for _, key := range keys[:len(keys)-1] {
+			if !ok {
+				return
+			}
+			m, ok = m[key].(map[string]interface{})
+		}
+		if ok {
+			m[keys[len(keys)-1]] = n
+		}
+	}
+}
+
+// flattenInto puts the values of the nested map m as attributes, with their keys joined
+// by dots and prefixed with prefix. Maps nested deeper than maxDepth are put as map values.
+func flattenInto(logger *zap.Logger, attrs pcommon.Map, prefix string, m map[string]interface{}, depth int, maxDepth int) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, ok := m[k].(map[string]interface{}); ok && depth < maxDepth {
+			if err := flattenInto(logger, attrs, key, nested, depth+1, maxDepth); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := convertToValue(logger, m[k], attrs.PutEmpty(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func convertToValue(logger *zap.Logger, src interface{}, dest pcommon.Value) error {
 	switch value := src.(type) {
 	case nil:
@@ -137,3 +364,80 @@ func convertToAttributeMap(logger *zap.Logger, value map[string]interface{}, des
 	}
 	return nil
 }
+
+// eventFields returns the body, time and severity fields used for the event, taking
+// the override for the sourcetype of the event into account.
+func eventFields(event *splunk.Event, config *Config) (bodyField, timeField, severityField string) {
+	bodyField, severityField = config.BodyField, config.SeverityField
+	override, ok := config.SourcetypeOverrides[event.SourceType]
+	if !ok {
+		return bodyField, "", severityField
+	}
+	if override.BodyField != "" {
+		bodyField = override.BodyField
+	}
+	if override.SeverityField != "" {
+		severityField = override.SeverityField
+	}
+	return bodyField, override.TimeField, severityField
+}
+
+// rawLineToLogRecord sets the body and timestamp of a log record from a line sent to
+// the raw endpoint. A line holding a JSON object with the Splunk reserved keys "_raw"
+// or "__time" takes its body or timestamp from them, and ts is used otherwise.
+func rawLineToLogRecord(line string, ts pcommon.Timestamp, logRecord plog.LogRecord) {
+	logRecord.Body().SetStr(line)
+	logRecord.SetTimestamp(ts)
+	if !strings.HasPrefix(strings.TrimSpace(line), "{") {
+		return
+	}
+	var reserved map[string]interface{}
+	if err := jsoniter.UnmarshalFromString(line, &reserved); err != nil {
+		return
+	}
+	if body, ok := reserved[rawBodyKey].(string); ok {
+		logRecord.Body().SetStr(body)
+	}
+	if t, ok := parseEpochSeconds(reserved[rawTimeKey]); ok {
+		logRecord.SetTimestamp(t)
+	}
+}
+
+// parseEpochSeconds converts a number of seconds since the epoch, given as a JSON
+// number or a string, to a timestamp.
+func parseEpochSeconds(v interface{}) (pcommon.Timestamp, bool) {
+	switch t := v.(type) {
+	case float64:
+		return pcommon.Timestamp(t * 1e9), true
+	case string:
+		seconds, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return 0, false
+		}
+		return pcommon.Timestamp(seconds * 1e9), true
+	default:
+		return 0, false
+	}
+}
+
+// parseEpochNanos parses a timestamp given as a number of nanoseconds since the epoch,
+// or as an RFC 3339 string with up to nanosecond precision.
+func parseEpochNanos(v interface{}) (pcommon.Timestamp, bool) {
+	switch t := v.(type) {
+	case int64:
+		return pcommon.Timestamp(t), true
+	case float64:
+		return pcommon.Timestamp(t), true
+	case string:
+		if nanos, err := strconv.ParseInt(t, 10, 64); err == nil {
+			return pcommon.Timestamp(nanos), true
+		}
+		ts, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return 0, false
+		}
+		return pcommon.NewTimestampFromTime(ts), true
+	default:
+		return 0, false
+	}
+}
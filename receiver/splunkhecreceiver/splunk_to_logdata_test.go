@@ -17,6 +17,7 @@ package splunkhecreceiver
 import (
 	"testing"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/pdata/pcommon"
@@ -151,6 +152,39 @@ func Test_SplunkHecToLogData(t *testing.T) {
 			}(),
 			wantErr: nil,
 		},
+		{
+			name: "multi_value_fields",
+			events: []*splunk.Event{
+				{
+					Time:       &time,
+					Host:       "localhost",
+					Source:     "mysource",
+					SourceType: "mysourcetype",
+					Index:      "myindex",
+					Event:      "value",
+					Fields: map[string]interface{}{
+						"foo":     "bar",
+						"count":   float64(3),
+						"tags":    []interface{}{"blue", "green"},
+						"objects": []interface{}{map[string]interface{}{"name": "a", "labels": map[string]interface{}{"env": "prod"}}},
+					},
+				},
+			},
+			hecConfig: defaultTestingHecConfig,
+			output: func() plog.ResourceLogsSlice {
+				logsSlice := createLogsSlice(nanoseconds)
+				attrs := logsSlice.At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes()
+				attrs.PutDouble("count", 3)
+				tags := attrs.PutEmptySlice("tags")
+				tags.AppendEmpty().SetStr("blue")
+				tags.AppendEmpty().SetStr("green")
+				object := attrs.PutEmptySlice("objects").AppendEmpty().SetEmptyMap()
+				object.PutEmptyMap("labels").PutStr("env", "prod")
+				object.PutStr("name", "a")
+				return logsSlice
+			}(),
+			wantErr: nil,
+		},
 		{
 			name: "nil_timestamp",
 			events: []*splunk.Event{
@@ -341,7 +375,7 @@ func Test_SplunkHecToLogData(t *testing.T) {
 	n := len(tests)
 	for _, tt := range tests[n-1:] {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := splunkHecToLogData(zap.NewNop(), tt.events, func(resource pcommon.Resource) {}, tt.hecConfig)
+			result, err := splunkHecToLogData(zap.NewNop(), tt.events, nil, func(resource pcommon.Resource) {}, tt.hecConfig)
 			assert.Equal(t, tt.wantErr, err)
 			require.Equal(t, tt.output.Len(), result.ResourceLogs().Len())
 			for i := 0; i < result.ResourceLogs().Len(); i++ {
@@ -351,6 +385,252 @@ func Test_SplunkHecToLogData(t *testing.T) {
 	}
 }
 
+func Test_SplunkHecToLogData_BodyField(t *testing.T) {
+	event := &splunk.Event{
+		Host:  "localhost",
+		Event: map[string]interface{}{"log": map[string]interface{}{"message": "nested"}},
+		Fields: map[string]interface{}{
+			"message": "from fields",
+		},
+	}
+
+	tests := []struct {
+		name      string
+		bodyField string
+		want      func(body pcommon.Value)
+	}{
+		{
+			name:      "event",
+			bodyField: "event",
+			want: func(body pcommon.Value) {
+				body.SetEmptyMap().PutEmptyMap("log").PutStr("message", "nested")
+			},
+		},
+		{
+			name:      "nested_event_key",
+			bodyField: "event.log.message",
+			want: func(body pcommon.Value) {
+				body.SetStr("nested")
+			},
+		},
+		{
+			name:      "field",
+			bodyField: "fields.message",
+			want: func(body pcommon.Value) {
+				body.SetStr("from fields")
+			},
+		},
+		{
+			name:      "missing_falls_back_to_whole_event",
+			bodyField: "fields.absent",
+			want: func(body pcommon.Value) {
+				body.SetStr(`{"host":"localhost","event":{"log":{"message":"nested"}},"fields":{"message":"from fields"}}`)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := *defaultTestingHecConfig
+			cfg.BodyField = tt.bodyField
+			result, err := splunkHecToLogData(zap.NewNop(), []*splunk.Event{event}, nil, nil, &cfg)
+			require.NoError(t, err)
+			want := pcommon.NewValueEmpty()
+			tt.want(want)
+			assert.Equal(t, want, result.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body())
+		})
+	}
+}
+
+func Test_SplunkHecToLogData_SplitByIndex(t *testing.T) {
+	events := []*splunk.Event{
+		{Host: "host1", Source: "source1", SourceType: "type1", Index: "index1", Event: "Event-1"},
+		{Host: "host2", Source: "source2", SourceType: "type2", Index: "index2", Event: "Event-2"},
+		{Host: "host3", Source: "source1", SourceType: "type1", Index: "index3", Event: "Event-3"},
+		{Host: "host1", Source: "source2", SourceType: "type2", Index: "index1", Event: "Event-4"},
+		{Host: "host2", Source: "source1", SourceType: "type1", Index: "index2", Event: "Event-5"},
+	}
+	cfg := *defaultTestingHecConfig
+	cfg.SplitByIndex = true
+
+	result, err := splunkHecToLogData(zap.NewNop(), events, nil, nil, &cfg)
+	require.NoError(t, err)
+
+	want := plog.NewLogs()
+	for _, index := range []string{"index1", "index2", "index3"} {
+		rl := want.ResourceLogs().AppendEmpty()
+		rl.Resource().Attributes().PutStr(splunk.DefaultIndexLabel, index)
+		sl := rl.ScopeLogs().AppendEmpty()
+		for _, event := range events {
+			if event.Index != index {
+				continue
+			}
+			lr := sl.LogRecords().AppendEmpty()
+			lr.Body().SetStr(event.Event.(string))
+			lr.Attributes().PutStr(conventions.AttributeHostName, event.Host)
+			lr.Attributes().PutStr(splunk.DefaultSourceLabel, event.Source)
+			lr.Attributes().PutStr(splunk.DefaultSourceTypeLabel, event.SourceType)
+		}
+	}
+	assert.Equal(t, want, result)
+}
+
+func Test_SplunkHecToLogData_HostAsLogAttribute(t *testing.T) {
+	events := []*splunk.Event{
+		{Host: "host1", Source: "source1", SourceType: "type1", Index: "index1", Event: "Event-1"},
+		{Host: "host2", Source: "source1", SourceType: "type1", Index: "index1", Event: "Event-2"},
+		{Source: "source2", SourceType: "type2", Event: "Event-3"},
+	}
+	cfg := *defaultTestingHecConfig
+	cfg.HecToOtelAttrs.Host = "splunk.host"
+	cfg.HostAsLogAttribute = true
+
+	result, err := splunkHecToLogData(zap.NewNop(), events, nil, nil, &cfg)
+	require.NoError(t, err)
+
+	want := plog.NewLogs()
+	rl := want.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr(splunk.DefaultSourceLabel, "source1")
+	rl.Resource().Attributes().PutStr(splunk.DefaultSourceTypeLabel, "type1")
+	rl.Resource().Attributes().PutStr(splunk.DefaultIndexLabel, "index1")
+	sl := rl.ScopeLogs().AppendEmpty()
+	lr := sl.LogRecords().AppendEmpty()
+	lr.Body().SetStr("Event-1")
+	lr.Attributes().PutStr("splunk.host", "host1")
+	lr = sl.LogRecords().AppendEmpty()
+	lr.Body().SetStr("Event-2")
+	lr.Attributes().PutStr("splunk.host", "host2")
+
+	rl = want.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr(splunk.DefaultSourceLabel, "source2")
+	rl.Resource().Attributes().PutStr(splunk.DefaultSourceTypeLabel, "type2")
+	rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("Event-3")
+	assert.Equal(t, want, result)
+}
+
+func Test_SplunkHecToLogData_Severity(t *testing.T) {
+	tests := []struct {
+		name          string
+		severityField string
+		event         *splunk.Event
+		wantText      string
+		wantNumber    plog.SeverityNumber
+	}{
+		{
+			name:          "disabled",
+			severityField: "",
+			event:         &splunk.Event{Event: "value", Fields: map[string]interface{}{"severity": "ERROR"}},
+		},
+		{
+			name:          "from_fields",
+			severityField: "fields.severity",
+			event:         &splunk.Event{Event: "value", Fields: map[string]interface{}{"severity": "WARN"}},
+			wantText:      "WARN",
+			wantNumber:    plog.SeverityNumberWarn,
+		},
+		{
+			name:          "from_event_case_insensitive",
+			severityField: "event.level",
+			event:         &splunk.Event{Event: map[string]interface{}{"level": "error"}},
+			wantText:      "error",
+			wantNumber:    plog.SeverityNumberError,
+		},
+		{
+			name:          "unknown_level",
+			severityField: "fields.severity",
+			event:         &splunk.Event{Event: "value", Fields: map[string]interface{}{"severity": "VERBOSE"}},
+			wantText:      "VERBOSE",
+			wantNumber:    plog.SeverityNumberUnspecified,
+		},
+		{
+			name:          "absent",
+			severityField: "fields.severity",
+			event:         &splunk.Event{Event: "value"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := *defaultTestingHecConfig
+			cfg.SeverityField = tt.severityField
+			result, err := splunkHecToLogData(zap.NewNop(), []*splunk.Event{tt.event}, nil, nil, &cfg)
+			require.NoError(t, err)
+			lr := result.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+			assert.Equal(t, tt.wantText, lr.SeverityText())
+			assert.Equal(t, tt.wantNumber, lr.SeverityNumber())
+		})
+	}
+}
+
+func Test_SplunkHecToLogData_TimeNanosField(t *testing.T) {
+	eventTime := 1675245600.123
+	tests := []struct {
+		name  string
+		event *splunk.Event
+		want  pcommon.Timestamp
+	}{
+		{
+			name:  "integer",
+			event: &splunk.Event{Time: &eventTime, Event: "value", Fields: map[string]interface{}{"timestamp_ns": int64(1675245600123456789)}},
+			want:  pcommon.Timestamp(1675245600123456789),
+		},
+		{
+			name:  "integer_string",
+			event: &splunk.Event{Time: &eventTime, Event: "value", Fields: map[string]interface{}{"timestamp_ns": "1675245600123456789"}},
+			want:  pcommon.Timestamp(1675245600123456789),
+		},
+		{
+			name:  "rfc3339nano",
+			event: &splunk.Event{Time: &eventTime, Event: "value", Fields: map[string]interface{}{"timestamp_ns": "2023-02-01T10:00:00.123456789Z"}},
+			want:  pcommon.Timestamp(1675245600123456789),
+		},
+		{
+			name:  "overrides_time_field",
+			event: &splunk.Event{SourceType: "nginx", Event: map[string]interface{}{"ts": 1675245700.0}, Fields: map[string]interface{}{"timestamp_ns": int64(1675245600123456789)}},
+			want:  pcommon.Timestamp(1675245600123456789),
+		},
+		{
+			name:  "absent",
+			event: &splunk.Event{Time: &eventTime, Event: "value"},
+			want:  pcommon.Timestamp(eventTime * 1e9),
+		},
+		{
+			name:  "invalid",
+			event: &splunk.Event{Time: &eventTime, Event: "value", Fields: map[string]interface{}{"timestamp_ns": "yesterday"}},
+			want:  pcommon.Timestamp(eventTime * 1e9),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := *defaultTestingHecConfig
+			cfg.TimeNanosField = "fields.timestamp_ns"
+			cfg.SourcetypeOverrides = map[string]SourcetypeOverride{"nginx": {TimeField: "event.ts"}}
+			result, err := splunkHecToLogData(zap.NewNop(), []*splunk.Event{tt.event}, nil, nil, &cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Timestamp())
+		})
+	}
+}
+
+func Test_setExactInteger(t *testing.T) {
+	rawEvent := []byte(`{"event":{"log":{"ts":1675245600123456789,"fraction":1.5}},"fields":{"timestamp_ns":1675245600123456789}}`)
+	var event splunk.Event
+	require.NoError(t, jsoniter.Unmarshal(rawEvent, &event))
+	// JSON numbers are decoded as float64 values, which cannot hold the timestamp exactly.
+	assert.IsType(t, float64(0), event.Fields["timestamp_ns"])
+
+	setExactInteger(&event, rawEvent, "fields.timestamp_ns", "")
+	assert.Equal(t, int64(1675245600123456789), event.Fields["timestamp_ns"])
+	setExactInteger(&event, rawEvent, "event.log.ts", eventField)
+	assert.Equal(t, int64(1675245600123456789), event.Event.(map[string]interface{})["log"].(map[string]interface{})["ts"])
+
+	// Fractional numbers and absent fields are left as they are.
+	setExactInteger(&event, rawEvent, "event.log.fraction", eventField)
+	assert.Equal(t, 1.5, event.Event.(map[string]interface{})["log"].(map[string]interface{})["fraction"])
+	setExactInteger(&event, rawEvent, "event.log.absent.ts", eventField)
+	setExactInteger(&event, rawEvent, "fields.absent", eventField)
+	_, ok := event.Fields["absent"]
+	assert.False(t, ok)
+}
+
 func updateResourceMap(pmap pcommon.Map, host, source, sourcetype, index string) {
 	pmap.PutStr("host.name", host)
 	pmap.PutStr("com.splunk.source", source)
@@ -424,3 +704,209 @@ func TestConvertToValueInvalidInMap(t *testing.T) {
 func TestConvertToValueInvalidInArray(t *testing.T) {
 	assert.Error(t, convertToValue(zap.NewNop(), []interface{}{splunk.Event{}}, pcommon.NewValueEmpty()))
 }
+
+func Test_rawLineToLogRecord(t *testing.T) {
+	const queryTs = pcommon.Timestamp(1e9)
+	tests := []struct {
+		name     string
+		line     string
+		wantBody string
+		wantTs   pcommon.Timestamp
+	}{
+		{
+			name:     "plain_text",
+			line:     "hello world",
+			wantBody: "hello world",
+			wantTs:   queryTs,
+		},
+		{
+			name:     "json_without_reserved_keys",
+			line:     `{"message":"hello"}`,
+			wantBody: `{"message":"hello"}`,
+			wantTs:   queryTs,
+		},
+		{
+			name:     "raw_key",
+			line:     `{"_raw":"hello world"}`,
+			wantBody: "hello world",
+			wantTs:   queryTs,
+		},
+		{
+			name:     "time_key_number",
+			line:     `{"__time":1.5,"message":"hello"}`,
+			wantBody: `{"__time":1.5,"message":"hello"}`,
+			wantTs:   pcommon.Timestamp(1.5e9),
+		},
+		{
+			name:     "raw_and_time_keys",
+			line:     `{"_raw":"hello world","__time":"2"}`,
+			wantBody: "hello world",
+			wantTs:   pcommon.Timestamp(2e9),
+		},
+		{
+			name:     "invalid_time_key",
+			line:     `{"_raw":"hello world","__time":"yesterday"}`,
+			wantBody: "hello world",
+			wantTs:   queryTs,
+		},
+		{
+			name:     "invalid_json",
+			line:     `{"_raw":"hello world"`,
+			wantBody: `{"_raw":"hello world"`,
+			wantTs:   queryTs,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logRecord := plog.NewLogRecord()
+			rawLineToLogRecord(tt.line, queryTs, logRecord)
+			assert.Equal(t, tt.wantBody, logRecord.Body().Str())
+			assert.Equal(t, tt.wantTs, logRecord.Timestamp())
+		})
+	}
+}
+
+func Test_SplunkHecToLogData_SourcetypeOverrides(t *testing.T) {
+	eventTime := 1.0
+	events := []*splunk.Event{
+		{
+			Time:       &eventTime,
+			SourceType: "nginx",
+			Event:      map[string]interface{}{"request": "GET /", "ts": 2.0, "level": "warn"},
+		},
+		{
+			Time:       &eventTime,
+			SourceType: "app",
+			Event:      "from event",
+			Fields:     map[string]interface{}{"msg": "from fields", "ts": "3"},
+		},
+		{
+			Time:       &eventTime,
+			SourceType: "other",
+			Event:      map[string]interface{}{"request": "GET /", "level": "error"},
+		},
+	}
+
+	cfg := *defaultTestingHecConfig
+	cfg.SeverityField = "event.level"
+	cfg.SourcetypeOverrides = map[string]SourcetypeOverride{
+		"nginx": {BodyField: "event.request", TimeField: "event.ts"},
+		"app":   {BodyField: "fields.msg", TimeField: "fields.ts", SeverityField: "fields.absent"},
+	}
+	result, err := splunkHecToLogData(zap.NewNop(), events, nil, nil, &cfg)
+	require.NoError(t, err)
+	require.Equal(t, 3, result.ResourceLogs().Len())
+
+	nginx := result.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, "GET /", nginx.Body().Str())
+	assert.Equal(t, pcommon.Timestamp(2e9), nginx.Timestamp())
+	assert.Equal(t, "warn", nginx.SeverityText(), "the global severity field applies when not overridden")
+
+	app := result.ResourceLogs().At(1).ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, "from fields", app.Body().Str())
+	assert.Equal(t, pcommon.Timestamp(3e9), app.Timestamp())
+	assert.Equal(t, "", app.SeverityText())
+
+	other := result.ResourceLogs().At(2).ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, map[string]interface{}{"request": "GET /", "level": "error"}, other.Body().Map().AsRaw())
+	assert.Equal(t, pcommon.Timestamp(1e9), other.Timestamp())
+	assert.Equal(t, "error", other.SeverityText())
+}
+
+func Test_SplunkHecToLogData_FlattenBody(t *testing.T) {
+	event := map[string]interface{}{
+		"message": "hello",
+		"count":   2.0,
+		"tags":    []interface{}{"a", "b"},
+		"http": map[string]interface{}{
+			"method": "GET",
+			"request": map[string]interface{}{
+				"headers": map[string]interface{}{"host": "example.com"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		event    interface{}
+		maxDepth int
+		want     map[string]interface{}
+	}{
+		{
+			name:     "object",
+			event:    event,
+			maxDepth: 5,
+			want: map[string]interface{}{
+				"message":                   "hello",
+				"count":                     2.0,
+				"tags":                      []interface{}{"a", "b"},
+				"http.method":               "GET",
+				"http.request.headers.host": "example.com",
+				"k0":                        "v0",
+			},
+		},
+		{
+			name:     "max_depth",
+			event:    event,
+			maxDepth: 2,
+			want: map[string]interface{}{
+				"message":      "hello",
+				"count":        2.0,
+				"tags":         []interface{}{"a", "b"},
+				"http.method":  "GET",
+				"http.request": map[string]interface{}{"headers": map[string]interface{}{"host": "example.com"}},
+				"k0":           "v0",
+			},
+		},
+		{
+			name:     "scalar",
+			event:    "hello",
+			maxDepth: 5,
+			want:     map[string]interface{}{"k0": "v0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := *defaultTestingHecConfig
+			cfg.FlattenBody = true
+			cfg.FlattenMaxDepth = tt.maxDepth
+			hecEvent := &splunk.Event{
+				Event:  tt.event,
+				Fields: map[string]interface{}{"k0": "v0"},
+			}
+			result, err := splunkHecToLogData(zap.NewNop(), []*splunk.Event{hecEvent}, nil, nil, &cfg)
+			require.NoError(t, err)
+			logRecord := result.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+			assert.Equal(t, tt.want, logRecord.Attributes().AsRaw())
+			// The body is kept.
+			assert.Equal(t, tt.event, logRecord.Body().AsRaw())
+		})
+	}
+}
+
+func Test_hasEmptyBody(t *testing.T) {
+	config := createDefaultConfig().(*Config)
+	config.BodyField = "event.message"
+	config.SourcetypeOverrides = map[string]SourcetypeOverride{
+		"heartbeat": {BodyField: "fields.beat"},
+	}
+	tests := []struct {
+		name  string
+		event splunk.Event
+		want  bool
+	}{
+		{name: "empty", event: splunk.Event{Event: map[string]interface{}{"message": ""}}, want: true},
+		{name: "whitespace", event: splunk.Event{Event: map[string]interface{}{"message": " \r\n\t"}}, want: true},
+		{name: "null", event: splunk.Event{Event: map[string]interface{}{"message": nil}}, want: true},
+		{name: "text", event: splunk.Event{Event: map[string]interface{}{"message": " hello "}}, want: false},
+		{name: "number", event: splunk.Event{Event: map[string]interface{}{"message": 0.0}}, want: false},
+		{name: "empty_map", event: splunk.Event{Event: map[string]interface{}{"message": map[string]interface{}{}}}, want: false},
+		{name: "no_body_field", event: splunk.Event{Event: map[string]interface{}{"msg": ""}}, want: false},
+		{name: "sourcetype_override", event: splunk.Event{SourceType: "heartbeat", Event: "not empty", Fields: map[string]interface{}{"beat": " "}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, hasEmptyBody(&tt.event, config))
+		})
+	}
+}
@@ -143,7 +143,8 @@ func buildAttributes(dimensions map[string]interface{}) pcommon.Map {
 	attributes.EnsureCapacity(len(dimensions))
 	for key, val := range dimensions {
 
-		if strings.HasPrefix(key, "metric_name") {
+		// Skip the reserved keys holding metric names and values.
+		if strings.HasPrefix(key, "metric_name") || key == "_value" {
 			continue
 		}
 		if key == "" || val == nil {
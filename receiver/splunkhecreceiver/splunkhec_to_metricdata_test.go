@@ -300,6 +300,33 @@ func Test_splunkV2ToMetricsData(t *testing.T) {
 			wantMetricsData: buildDefaultMetricsData(nanos),
 			hecConfig:       defaultTestingHecConfig,
 		},
+		{
+			name: "reserved_keys_excluded_from_multiple_metrics",
+			splunkDataPoint: func() *splunk.Event {
+				pt := buildDefaultSplunkDataPt()
+				pt.Fields["metric_name:other"] = int64Ptr(14)
+				pt.Fields["_value"] = "42"
+				pt.Fields["region"] = "us-west-1"
+				return pt
+			}(),
+			wantMetricsData: func() pmetric.Metrics {
+				metrics := buildDefaultMetricsData(nanos)
+				mts := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+				mts.At(0).Gauge().DataPoints().At(0).Attributes().PutStr("region", "us-west-1")
+
+				metricPt := mts.AppendEmpty()
+				metricPt.SetName("other")
+				intPt := metricPt.SetEmptyGauge().DataPoints().AppendEmpty()
+				intPt.SetIntValue(14)
+				intPt.SetTimestamp(pcommon.Timestamp(nanos))
+				intPt.Attributes().PutStr("k0", "v0")
+				intPt.Attributes().PutStr("k1", "v1")
+				intPt.Attributes().PutStr("k2", "v2")
+				intPt.Attributes().PutStr("region", "us-west-1")
+				return metrics
+			}(),
+			hecConfig: defaultTestingHecConfig,
+		},
 	}
 
 	for _, tt := range tests {